@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"os"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/storage"
@@ -10,8 +13,11 @@ import (
 )
 
 type uriDeckAccessor struct {
-	deck fyne.URI
-	db   fyne.URI
+	deck      fyne.URI
+	db        fyne.URI
+	backup    fyne.URI
+	corrupted fyne.URI
+	history   fyne.URI
 }
 
 func (u *uriDeckAccessor) CardsReader() (io.ReadCloser, error) {
@@ -27,20 +33,157 @@ func (u *uriDeckAccessor) MetaReader() (io.ReadCloser, error) {
 }
 
 func (u *uriDeckAccessor) MetaWriter() (io.WriteCloser, error) {
-	w, err := storage.Writer(u.db)
+	return &atomicWriter{uri: u.db, backup: u.backup, valid: flashdown.ValidMeta}, nil
+}
+
+func (u *uriDeckAccessor) CorruptedMetaWriter() (io.WriteCloser, error) {
+	if u.corrupted == nil {
+		return nil, fmt.Errorf("Cannot keep %s", u.db.Name())
+	}
+	return storage.Writer(u.corrupted)
+}
+
+func (u *uriDeckAccessor) MetaBackupReader() (io.ReadCloser, error) {
+	if u.backup == nil {
+		return nil, fmt.Errorf("No backup for %s", u.db.Name())
+	}
+	return storage.Reader(u.backup)
+}
+
+// atomicWriter buffers the data written and replaces the file when closed.
+// The data is written to a temporary file which is moved over the file, so
+// that a crash never leaves a partially written file. The previous version
+// of the file is kept as a backup if it is valid.
+type atomicWriter struct {
+	bytes.Buffer
+	uri    fyne.URI
+	backup fyne.URI               // nil for no backup
+	valid  func(data []byte) bool // nil if any content is valid
+}
+
+// writeFile writes data to uri and flushes it to the disk when possible.
+func writeFile(uri fyne.URI, data []byte) error {
+	w, err := storage.Writer(uri)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	if f, ok := w.(interface{ Sync() error }); ok {
+		if err := f.Sync(); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+func (a *atomicWriter) Close() error {
+	// A corrupted file doesn't replace the backup.
+	if r, err := storage.Reader(a.uri); err == nil && a.backup != nil {
+		previous, err := io.ReadAll(r)
+		r.Close()
+		if err == nil && (a.valid == nil || a.valid(previous)) {
+			if err := writeFile(a.backup, previous); err != nil {
+				return err
+			}
+		}
+	}
+	tmp, err := storage.ParseURI(a.uri.String() + ".tmp")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(tmp, a.Bytes()); err != nil {
+		return err
+	}
+	if err := storage.Move(tmp, a.uri); err == nil {
+		return nil
+	}
+	// Some repositories, like the Android ones, can't move files: the file
+	// is overwritten. The temporary file is only removed once done, so a
+	// crash leaves the previous version in the backup and the new one in
+	// the temporary file.
+	if err := writeFile(a.uri, a.Bytes()); err != nil {
+		return err
+	}
+	return storage.Delete(tmp)
+}
+
+func (u *uriDeckAccessor) HistoryReader() (io.ReadCloser, error) {
+	return storage.Reader(u.history)
+}
+
+// appendWriter buffers the data written and appends it to the file when
+// closed. Local files are opened in append mode. The other repositories
+// can't append: the file is rewritten with an atomicWriter.
+type appendWriter struct {
+	bytes.Buffer
+	uri    fyne.URI
+	backup fyne.URI // used when the file is rewritten
+}
+
+func (a *appendWriter) Close() error {
+	if a.uri.Scheme() == "file" {
+		f, err := os.OpenFile(a.uri.Path(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(a.Bytes()); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	w := &atomicWriter{uri: a.uri, backup: a.backup}
+	if r, err := storage.Reader(a.uri); err == nil {
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	w.Write(a.Bytes())
+	return w.Close()
+}
+
+func (u *uriDeckAccessor) HistoryWriter() (io.WriteCloser, error) {
+	backup, err := storage.ParseURI(u.history.String() + ".bak")
+	if err != nil {
+		return nil, err
+	}
+	return &appendWriter{uri: u.history, backup: backup}, nil
+}
+
+func (u *uriDeckAccessor) MediaReader(path string) (io.ReadCloser, error) {
+	dir, err := storage.Parent(u.deck)
+	if err != nil {
+		return nil, err
+	}
+	uri, err := storage.Child(dir, path)
 	if err != nil {
 		return nil, err
 	}
-	return w, err
+	return storage.Reader(uri)
 }
 
 func (u *uriDeckAccessor) DeckName() string {
 	return u.deck.Name()
 }
 
-func NewDeckAccessor(deck, db fyne.URI) flashdown.DeckAccessor {
+func NewDeckAccessor(deck, db, history fyne.URI) flashdown.DeckAccessor {
+	backup, _ := storage.ParseURI(db.String() + ".bak")
+	corrupted, _ := storage.ParseURI(db.String() + ".corrupt")
 	return &uriDeckAccessor{
-		deck: deck,
-		db:   db,
+		deck:      deck,
+		db:        db,
+		backup:    backup,
+		corrupted: corrupted,
+		history:   history,
 	}
 }
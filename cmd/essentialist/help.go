@@ -22,6 +22,9 @@ const (
 -   '5' - Perfect response
 -   's' or 'n' - skip the card and go to the next card
 -   'p' - go to the previous card
+-   'b' - bury the card until tomorrow (use the Suspend button to stop
+    asking it, and the statistics screen to unsuspend it)
+-   'u' - undo the last review or skip
 
 ----
 ### Home screen shortcuts:
@@ -30,6 +33,9 @@ const (
 -   Enter - start quick session
 -   'h' - show help menu
 -   's' - show settings menu
+-   't' - show statistics
+-   'g' - study the cards with some tags
+-   'r' - resume the interrupted session
 `
 )
 
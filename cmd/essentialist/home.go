@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 
@@ -15,6 +17,7 @@ import (
 type HomeScreen struct {
 	accessors []flashdown.DeckAccessor
 	decks     []*flashdown.Deck
+	corrupted map[int]bool // decks with corrupted progress
 	cardsNb   int
 }
 
@@ -22,6 +25,7 @@ func NewHomeScreen(accessors []flashdown.DeckAccessor) Screen {
 	return &HomeScreen{
 		accessors: accessors,
 		decks:     make([]*flashdown.Deck, len(accessors)),
+		corrupted: make(map[int]bool),
 		cardsNb:   getRepetitionLenght(),
 	}
 }
@@ -38,6 +42,12 @@ func (s *HomeScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 				app.Display(NewHelpScreen())
 			case fyne.KeyS:
 				app.Display(NewSettingsScreen())
+			case fyne.KeyT:
+				s.showStats(app)
+			case fyne.KeyG:
+				s.selectTags(app)
+			case fyne.KeyR:
+				s.resumeSession(app)
 			}
 		} else {
 			switch key.Physical {
@@ -49,18 +59,25 @@ func (s *HomeScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 				app.Display(NewSettingsScreen())
 			case fyne.HardwareKey{ScanCode: 43}: // H
 				app.Display(NewHelpScreen())
+			case fyne.HardwareKey{ScanCode: 28}: // T
+				s.showStats(app)
+			case fyne.HardwareKey{ScanCode: 42}: // G
+				s.selectTags(app)
+			case fyne.HardwareKey{ScanCode: 27}: // R
+				s.resumeSession(app)
 			}
 		}
 	}
 }
 
-func (s *HomeScreen) startQuickSession(app Application) {
-	// We need to load remaning decks not yet loaded by by list widget.
+// loadDecks returns all the decks which can be loaded, including those not
+// yet loaded by the list widget.
+func (s *HomeScreen) loadDecks() []*flashdown.Deck {
 	decks := make([]*flashdown.Deck, 0, len(s.accessors))
 	for i, a := range s.accessors {
 		if s.decks[i] == nil {
 			deck, err := flashdown.NewDeck(a)
-			if err != nil {
+			if err == nil {
 				s.decks[i] = deck
 				decks = append(decks, deck)
 			}
@@ -68,7 +85,12 @@ func (s *HomeScreen) startQuickSession(app Application) {
 			decks = append(decks, s.decks[i])
 		}
 	}
-	game := flashdown.NewGame(s.cardsNb, decks...)
+	return decks
+}
+
+func (s *HomeScreen) startQuickSession(app Application) {
+	decks := s.loadDecks()
+	game := flashdown.NewGameWithOptions(sessionOptions(s.cardsNb), decks...)
 	if game.IsFinished() {
 		app.Display(NewCongratsScreen(game))
 	} else {
@@ -76,6 +98,80 @@ func (s *HomeScreen) startQuickSession(app Application) {
 	}
 }
 
+// resumeSession continues the session interrupted last time. If its decks
+// changed too much, the session is forgotten.
+func (s *HomeScreen) resumeSession(app Application) {
+	session := getSession()
+	if session == nil {
+		return
+	}
+	game, err := flashdown.ResumeGame(session, s.loadDecks()...)
+	if err != nil {
+		clearSession()
+		app.Display(s)
+		dialog.ShowError(fmt.Errorf("Cannot resume the session: %w", err),
+			app.Window())
+		return
+	}
+	app.Display(NewQuestionScreen(game))
+}
+
+// selectTags asks which tags to study and starts a session with the cards
+// of all the decks which have one of them.
+func (s *HomeScreen) selectTags(app Application) {
+	decks := s.loadDecks()
+	tags := flashdown.Tags(decks...)
+	if len(tags) == 0 {
+		dialog.ShowInformation("Tags", "No tagged card found.", app.Window())
+		return
+	}
+	check := widget.NewCheckGroup(tags, nil)
+	content := container.NewVScroll(check)
+	content.SetMinSize(fyne.NewSize(240, 240))
+	start := func(ok bool) {
+		if !ok || len(check.Selected) == 0 {
+			return
+		}
+		options := sessionOptions(s.cardsNb)
+		options.Filter = flashdown.TagFilter(check.Selected...)
+		game := flashdown.NewGameWithOptions(options, decks...)
+		if game.IsFinished() {
+			app.Display(NewCongratsScreen(game))
+		} else {
+			app.Display(NewQuestionScreen(game))
+		}
+	}
+	dialog.ShowCustomConfirm("Study tags", "Start", "Cancel", content, start,
+		app.Window())
+}
+
+// recoverDeck offers to restore the backup of a deck with corrupted progress.
+func (s *HomeScreen) recoverDeck(app Application, i int) {
+	name := s.accessors[i].DeckName()
+	message := fmt.Sprintf("The progress of %s can't be read.\nRestore the last backup?", name)
+	dialog.ShowConfirm("Corrupted progress", message, func(ok bool) {
+		if !ok {
+			return
+		}
+		restored, err := flashdown.RecoverMeta(s.accessors[i])
+		if err != nil {
+			app.Display(NewErrorScreen(err))
+			return
+		}
+		s.decks[i] = nil
+		delete(s.corrupted, i)
+		s.Show(app)
+		if !restored {
+			dialog.ShowInformation("Corrupted progress",
+				"No valid backup found, the progress is reset.", app.Window())
+		}
+	}, app.Window())
+}
+
+func (s *HomeScreen) showStats(app Application) {
+	app.Display(NewStatsScreen(s.loadDecks()))
+}
+
 func (s *HomeScreen) updateDeckButton(app Application, label *widget.Label, i int) {
 	deck := s.decks[i]
 	toReview, total := deck.Stats()
@@ -83,7 +179,10 @@ func (s *HomeScreen) updateDeckButton(app Application, label *widget.Label, i in
 	if total != 0 {
 		success = 100 * ((float64(total) - float64(toReview)) / float64(total))
 	}
-	content := fmt.Sprintf("%s (%.0f%% of %d)", deck.Name, success, total)
+	content := fmt.Sprintf("%s (%.0f%% of %d)", deck.Title(), success, total)
+	if deck.Settings.Description != "" {
+		content += " — " + deck.Settings.Description
+	}
 	label.SetText(content)
 }
 
@@ -99,14 +198,28 @@ func (s *HomeScreen) deckList(app Application) fyne.CanvasObject {
 			return len(s.decks)
 		},
 		func() fyne.CanvasObject {
-			return widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{})
+			label := widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{})
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
 		},
 		func(i widget.ListItemID, o fyne.CanvasObject) {
 			label := o.(*widget.Label)
+			if s.corrupted[i] {
+				label.SetText(fmt.Sprintf("Corrupted progress of %s, select to recover",
+					s.accessors[i].DeckName()))
+				return
+			}
 			if s.decks[i] == nil { // lazy loading
 				var err error
 				s.decks[i], err = flashdown.NewDeck(s.accessors[i])
-				if err != nil {
+				if errors.Is(err, flashdown.ErrCorruptedMeta) {
+					s.decks[i] = flashdown.NewEmptyDeck(
+						s.accessors[i].DeckName())
+					s.corrupted[i] = true
+					label.SetText(fmt.Sprintf("Corrupted progress of %s, select to recover",
+						s.accessors[i].DeckName()))
+					return
+				} else if err != nil {
 					s.decks[i] = flashdown.NewEmptyDeck(
 						s.accessors[i].DeckName())
 					label.SetText(fmt.Sprintf("Failed to load %s: %s",
@@ -117,7 +230,12 @@ func (s *HomeScreen) deckList(app Application) fyne.CanvasObject {
 			s.updateDeckButton(app, label, i)
 		})
 	list.OnSelected = func(id widget.ListItemID) {
-		game := flashdown.NewGame(getRepetitionLenght(), s.decks[id])
+		if s.corrupted[id] {
+			s.recoverDeck(app, id)
+			return
+		}
+		game := flashdown.NewGameWithOptions(sessionOptions(getRepetitionLenght()),
+			s.decks[id])
 		if game.IsFinished() {
 			app.Display(NewCongratsScreen(game))
 		} else {
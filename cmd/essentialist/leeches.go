@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// LeechesScreen lists the cards failed too often so that they can be
+// rewritten.
+type LeechesScreen struct {
+	decks []*flashdown.Deck
+}
+
+func NewLeechesScreen(decks []*flashdown.Deck) Screen {
+	return &LeechesScreen{decks: decks}
+}
+
+func (s *LeechesScreen) leeches() fyne.CanvasObject {
+	leeches := flashdown.Leeches(0, s.decks...)
+	if len(leeches) == 0 {
+		return container.NewCenter(widget.NewLabel("No leech, well done!"))
+	}
+	objects := make([]fyne.CanvasObject, 0, len(leeches)+1)
+	objects = append(objects, widget.NewLabel(
+		"These cards were failed too often, consider rewriting them:"))
+	for _, leech := range leeches {
+		status := ""
+		if leech.Suspended {
+			status = ", suspended"
+		}
+		question := widget.NewLabelWithStyle(leech.Question,
+			fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+		question.Wrapping = fyne.TextWrapWord
+		location := widget.NewLabel(fmt.Sprintf("%s, line %d — %d lapses%s",
+			leech.Deck, leech.Line, leech.Lapses, status))
+		location.Wrapping = fyne.TextWrapWord
+		objects = append(objects, widget.NewSeparator(), question, location)
+	}
+	return container.New(layout.NewVBoxLayout(), objects...)
+}
+
+func (s *LeechesScreen) Show(app Application) {
+	window := app.Window()
+	stats := widget.NewButton("Stats", func() {
+		app.Display(NewStatsScreen(s.decks))
+	})
+	home := widget.NewButton("Home", func() {
+		app.Display(NewSplashScreen())
+	})
+	topBar := newTopBar("Leeches", stats, home)
+	center := container.NewVScroll(container.New(NewMaxWidthCenterLayout(640),
+		s.leeches()))
+	window.SetContent(container.New(layout.NewBorderLayout(
+		topBar, nil, nil, nil), topBar, center))
+	window.Canvas().SetOnTypedKey(EscapeKeyHandler(app))
+}
+
+func (s *LeechesScreen) Hide(app Application) {
+	app.Window().Canvas().SetOnTypedKey(nil)
+}
@@ -5,6 +5,8 @@ import (
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+
+	flashdown "github.com/lugu/flashdown/internal"
 )
 
 func parseArgs() {
@@ -17,6 +19,7 @@ func main() {
 	parseArgs()
 	application := app.NewWithID("essentialist")
 	application.Settings().SetTheme(getTheme())
+	flashdown.DefaultScheduler = getScheduler()
 	window := application.NewWindow("Essentialist")
 	window.Resize(fyne.NewSize(640, 480))
 	NewApplication(application, window).Display(NewSplashScreen())
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"fmt"
 	"image/color"
+	"unicode/utf8"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
 	flashdown "github.com/lugu/flashdown/internal"
@@ -50,6 +54,41 @@ func space() fyne.CanvasObject {
 	return layout.NewSpacer()
 }
 
+// undo cancels the last review and shows its answer again.
+func undo(app Application, game *flashdown.Game) {
+	if game.Undo() {
+		app.Display(NewAnswerScreen(game))
+	}
+}
+
+// setAside suspends or buries the current card and shows the next one.
+func setAside(app Application, game *flashdown.Game, suspend bool) {
+	if suspend {
+		game.Suspend()
+	} else {
+		game.Bury()
+	}
+	if game.IsFinished() {
+		saveGame(game)
+		app.Display(NewCongratsScreen(game))
+	} else {
+		app.Display(NewQuestionScreen(game))
+	}
+}
+
+// setAsideButtons returns the buttons to bury and to suspend the current
+// card.
+func setAsideButtons(app Application, game *flashdown.Game) []fyne.CanvasObject {
+	return []fyne.CanvasObject{
+		widget.NewButton("Bury until tomorrow", func() {
+			setAside(app, game, false)
+		}),
+		widget.NewButton("Suspend", func() {
+			setAside(app, game, true)
+		}),
+	}
+}
+
 type QuestionScreen struct {
 	game *flashdown.Game
 }
@@ -58,6 +97,10 @@ func NewQuestionScreen(game *flashdown.Game) Screen {
 	return &QuestionScreen{game: game}
 }
 
+func (s *QuestionScreen) Game() *flashdown.Game {
+	return s.game
+}
+
 func (s *QuestionScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 	return func(key *fyne.KeyEvent) {
 		if key.Name != "" {
@@ -65,7 +108,7 @@ func (s *QuestionScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 			case fyne.KeySpace, fyne.KeyReturn:
 				app.Display(NewAnswerScreen(s.game))
 			case fyne.KeyQ, fyne.KeyEscape:
-				s.game.Save()
+				saveGame(s.game)
 				app.Display(NewSplashScreen())
 			case fyne.KeyS, fyne.KeyN:
 				s.game.Skip()
@@ -77,13 +120,17 @@ func (s *QuestionScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 			case fyne.KeyP:
 				s.game.Previous()
 				app.Display(NewQuestionScreen(s.game))
+			case fyne.KeyU:
+				undo(app, s.game)
+			case fyne.KeyB:
+				setAside(app, s.game, false)
 			case fyne.KeyW:
-				s.game.Save()
+				saveGame(s.game)
 			}
 		} else {
 			switch key.Physical {
 			case fyne.HardwareKey{ScanCode: 9}, fyne.HardwareKey{ScanCode: 24}: // Escape
-				s.game.Save()
+				saveGame(s.game)
 				app.Display(NewSplashScreen())
 			case fyne.HardwareKey{ScanCode: 39}, fyne.HardwareKey{ScanCode: 57}: // 's' or 'n'
 				s.game.Skip()
@@ -95,16 +142,88 @@ func (s *QuestionScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 			case fyne.HardwareKey{ScanCode: 33}: // 'p'
 				s.game.Previous()
 				app.Display(NewQuestionScreen(s.game))
+			case fyne.HardwareKey{ScanCode: 30}: // 'u'
+				undo(app, s.game)
+			case fyne.HardwareKey{ScanCode: 56}: // 'b'
+				setAside(app, s.game, false)
 			case fyne.HardwareKey{ScanCode: 25}: // 'w'
-				s.game.Save()
+				saveGame(s.game)
 			}
 		}
 	}
 }
 
+// clozeStyler removes the marks of the cloze deletions from the segments
+// of a RichText and colors the blanked and the revealed deletions.
+type clozeStyler struct {
+	color fyne.ThemeColorName // color of the current deletion, none outside
+}
+
+func (c *clozeStyler) segments(segs []widget.RichTextSegment) []widget.RichTextSegment {
+	styled := make([]widget.RichTextSegment, 0, len(segs))
+	for _, seg := range segs {
+		switch t := seg.(type) {
+		case *widget.TextSegment:
+			styled = append(styled, c.text(t)...)
+		case *widget.ParagraphSegment:
+			t.Texts = c.segments(t.Texts)
+			styled = append(styled, t)
+		case *ListSegment:
+			t.Items = c.segments(t.Items)
+			styled = append(styled, t)
+		default:
+			styled = append(styled, seg)
+		}
+	}
+	return styled
+}
+
+// text splits a segment at the marks of the deletions. The pieces stay on
+// the same line: only the last one keeps the Inline style of the segment.
+func (c *clozeStyler) text(seg *widget.TextSegment) []widget.RichTextSegment {
+	pieces := make([]widget.RichTextSegment, 0, 1)
+	start := 0
+	add := func(end int) {
+		if end == start {
+			return
+		}
+		piece := *seg
+		piece.Text = seg.Text[start:end]
+		piece.Style.Inline = true
+		if c.color != "" {
+			piece.Style.ColorName = c.color
+			piece.Style.TextStyle.Bold = true
+		}
+		pieces = append(pieces, &piece)
+	}
+	for i, r := range seg.Text {
+		next := c.color
+		switch r {
+		case flashdown.ClozeBlankStart:
+			next = theme.ColorNamePrimary
+		case flashdown.ClozeRevealStart:
+			next = theme.ColorNameSuccess
+		case flashdown.ClozeBlankEnd, flashdown.ClozeRevealEnd:
+			next = ""
+		default:
+			continue
+		}
+		add(i)
+		c.color = next
+		start = i + utf8.RuneLen(r)
+	}
+	add(len(seg.Text))
+	if len(pieces) == 0 {
+		return []widget.RichTextSegment{seg}
+	}
+	pieces[len(pieces)-1].(*widget.TextSegment).Style.Inline = seg.Style.Inline
+	return pieces
+}
+
 // TODO: make the test selectable
 func card(md string) fyne.CanvasObject {
 	richText := NewRichTextFromMarkdown(md)
+	richText.Segments = (&clozeStyler{}).segments(richText.Segments)
 	width := richText.MinSize().Width
 	richText.Wrapping = fyne.TextWrapWord
 	return container.New(NewMaxWidthCenterLayout(width), richText)
@@ -114,11 +233,13 @@ func (s *QuestionScreen) Show(app Application) {
 	window := app.Window()
 
 	topBar := newProgressTopBar(app, s.game)
-	question := card("### " + s.game.Question())
+	question := card("### " + s.game.MarkedQuestion())
 	button := continueButton(app, s.game)
 
+	others := container.New(layout.NewGridLayout(2),
+		setAsideButtons(app, s.game)...)
 	vbox := container.New(layout.NewVBoxLayout(), topBar, space(), question,
-		space(), button)
+		space(), others, button)
 	window.SetContent(vbox)
 	window.Canvas().SetOnTypedKey(s.keyHandler(app))
 }
@@ -135,6 +256,10 @@ func NewAnswerScreen(game *flashdown.Game) Screen {
 	return &AnswerScreen{game: game}
 }
 
+func (s *AnswerScreen) Game() *flashdown.Game {
+	return s.game
+}
+
 func (s *AnswerScreen) answersButton(app Application) *fyne.Container {
 	bt := func(label string, score flashdown.Score) *widget.Button {
 		return widget.NewButton(label,
@@ -150,17 +275,32 @@ func (s *AnswerScreen) answersButton(app Application) *fyne.Container {
 		bt("Incorrect easy", flashdown.IncorrectEasy),
 		bt("Correct easy", flashdown.CorrectEasy),
 	}
-	return container.New(layout.NewGridLayout(2), buttons...)
+	undoButton := widget.NewButton("Undo last review", func() {
+		undo(app, s.game)
+	})
+	if !s.game.CanUndo() {
+		undoButton.Disable()
+	}
+	grid := container.New(layout.NewGridLayout(2), buttons...)
+	others := container.New(layout.NewGridLayout(3),
+		append([]fyne.CanvasObject{undoButton},
+			setAsideButtons(app, s.game)...)...)
+	return container.New(layout.NewVBoxLayout(), grid, others)
 }
 
 func (s *AnswerScreen) reviewScore(app Application, score flashdown.Score) {
 	s.game.Review(score)
 	if s.game.IsFinished() {
-		s.game.Save()
+		saveGame(s.game)
 		app.Display(NewCongratsScreen(s.game))
 	} else {
 		app.Display(NewQuestionScreen(s.game))
 	}
+	if leech, ok := s.game.SuspendedLeech(); ok {
+		dialog.ShowInformation("Leech suspended",
+			fmt.Sprintf("This card was forgotten %d times: it is suspended.\n\n%s",
+				leech.Meta.Lapses, leech.Question), app.Window())
+	}
 }
 
 func (s *AnswerScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
@@ -180,7 +320,7 @@ func (s *AnswerScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 			case fyne.Key5:
 				s.reviewScore(app, flashdown.PerfectRecall)
 			case fyne.KeyQ, fyne.KeyEscape:
-				s.game.Save()
+				saveGame(s.game)
 				app.Display(NewSplashScreen())
 			case fyne.KeyS, fyne.KeyN:
 				s.game.Skip()
@@ -192,13 +332,17 @@ func (s *AnswerScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 			case fyne.KeyP:
 				s.game.Previous()
 				app.Display(NewQuestionScreen(s.game))
+			case fyne.KeyU:
+				undo(app, s.game)
+			case fyne.KeyB:
+				setAside(app, s.game, false)
 			case fyne.KeyW:
-				s.game.Save()
+				saveGame(s.game)
 			}
 		} else {
 			switch key.Physical {
 			case fyne.HardwareKey{ScanCode: 9}, fyne.HardwareKey{ScanCode: 24}: // Escape
-				s.game.Save()
+				saveGame(s.game)
 				app.Display(NewSplashScreen())
 			case fyne.HardwareKey{ScanCode: 39}, fyne.HardwareKey{ScanCode: 57}: // 's' or 'n'
 				s.game.Skip()
@@ -210,8 +354,12 @@ func (s *AnswerScreen) keyHandler(app Application) func(*fyne.KeyEvent) {
 			case fyne.HardwareKey{ScanCode: 33}: // 'p'
 				s.game.Previous()
 				app.Display(NewQuestionScreen(s.game))
+			case fyne.HardwareKey{ScanCode: 30}: // 'u'
+				undo(app, s.game)
+			case fyne.HardwareKey{ScanCode: 56}: // 'b'
+				setAside(app, s.game, false)
 			case fyne.HardwareKey{ScanCode: 25}: // 'w'
-				s.game.Save()
+				saveGame(s.game)
 			}
 		}
 	}
@@ -221,9 +369,9 @@ func (s *AnswerScreen) Show(app Application) {
 	window := app.Window()
 	topBar := newProgressTopBar(app, s.game)
 
-	question := card("### " + s.game.Question())
+	question := card("### " + s.game.MarkedQuestion())
 	line := canvas.NewLine(color.Gray16{0xaaaa})
-	answer := card(s.game.Answer())
+	answer := card(s.game.MarkedAnswer())
 
 	buttons := s.answersButton(app)
 	vbox := container.New(layout.NewVBoxLayout(), topBar, space(), question,
@@ -2,6 +2,8 @@ package main
 
 import (
 	"fyne.io/fyne/v2"
+
+	flashdown "github.com/lugu/flashdown/internal"
 )
 
 type Application interface {
@@ -15,6 +17,11 @@ type Screen interface {
 	Hide(app Application)
 }
 
+// gameScreen is implemented by the screens of a session in progress.
+type gameScreen interface {
+	Game() *flashdown.Game
+}
+
 type application struct {
 	app    fyne.App
 	win    fyne.Window
@@ -40,9 +47,25 @@ func (a *application) Display(screen Screen) {
 	screen.Show(a)
 }
 
+// saveSession saves the session in progress, if any.
+func (a *application) saveSession() {
+	if s, ok := a.screen.(gameScreen); ok {
+		saveGame(s.Game())
+	}
+}
+
+// NewApplication returns an Application which saves the session in progress
+// when the window is closed or when the application goes to the
+// background, since mobile systems may kill it without notice.
 func NewApplication(app fyne.App, window fyne.Window) Application {
-	return &application{
+	a := &application{
 		app: app,
 		win: window,
 	}
+	window.SetCloseIntercept(func() {
+		a.saveSession()
+		window.Close()
+	})
+	app.Lifecycle().SetOnExitedForeground(a.saveSession)
+	return a
 }
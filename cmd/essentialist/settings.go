@@ -7,6 +7,7 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
 	"fyne.io/fyne/v2/widget"
 
 	flashdown "github.com/lugu/flashdown/internal"
@@ -38,6 +39,33 @@ func (s *SettingsScreen) importDirectoryButton(app Application) *widget.Button {
 	})
 }
 
+func (s *SettingsScreen) importCSVButton(app Application) *widget.Button {
+	importCallback := func(r fyne.URIReadCloser, err error) {
+		if err != nil {
+			app.Display(NewErrorScreen(err))
+			return
+		}
+		if r == nil {
+			return
+		}
+		defer r.Close()
+		result, err := importCSV(r)
+		if err != nil {
+			app.Display(NewErrorScreen(fmt.Errorf("Cannot import %s: %w",
+				r.URI().Name(), err)))
+			return
+		}
+		message := fmt.Sprintf("%d cards imported, %d rows skipped.",
+			result.Notes, result.Skipped)
+		dialog.ShowInformation("Import CSV", message, app.Window())
+	}
+	return widget.NewButton("Import CSV", func() {
+		open := dialog.NewFileOpen(importCallback, app.Window())
+		open.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".tsv", ".txt"}))
+		open.Show()
+	})
+}
+
 func (s *SettingsScreen) changeDirectoryButton(app Application) *widget.Button {
 	changeDirectoryCallback := func(d fyne.ListableURI, err error) {
 		if err != nil {
@@ -94,6 +122,241 @@ func (s *SettingsScreen) selectRepetition(app Application) *widget.Select {
 	return repetitions
 }
 
+func (s *SettingsScreen) selectScheduler(app Application) *widget.Select {
+	selections := []string{
+		"Algorithm: SM-2",
+		"Algorithm: FSRS",
+	}
+	values := []flashdown.Scheduler{
+		flashdown.NewSM2Scheduler(),
+		flashdown.NewFSRSScheduler(),
+	}
+	onChange := func(selected string) {
+		for i, s := range selections {
+			if s == selected {
+				setScheduler(values[i])
+				return
+			}
+		}
+	}
+	schedulers := widget.NewSelect(selections, onChange)
+	schedulers.Alignment = fyne.TextAlignCenter
+	name := getScheduler().Name()
+	for i, v := range values {
+		if v.Name() == name {
+			schedulers.SetSelected(selections[i])
+			break
+		}
+	}
+	return schedulers
+}
+
+// exportDecks writes all the decks in a format chosen by the user.
+func (s *SettingsScreen) exportDecks(app Application, format string) {
+	accessors, err := loadDecks()
+	if err != nil {
+		app.Display(NewErrorScreen(err))
+		return
+	}
+	decks := make([]*flashdown.Deck, 0, len(accessors))
+	for _, a := range accessors {
+		deck, err := flashdown.NewDeck(a)
+		if err != nil {
+			app.Display(NewErrorScreen(err))
+			return
+		}
+		decks = append(decks, deck)
+	}
+	save := dialog.NewFileSave(func(w fyne.URIWriteCloser, err error) {
+		if err != nil {
+			app.Display(NewErrorScreen(err))
+			return
+		}
+		if w == nil {
+			return
+		}
+		if err := flashdown.Export(w, format, decks...); err != nil {
+			w.Close()
+			app.Display(NewErrorScreen(err))
+			return
+		}
+		if err := w.Close(); err != nil {
+			app.Display(NewErrorScreen(err))
+		}
+	}, app.Window())
+	extension := format
+	if format == flashdown.FormatAnkiText {
+		extension = "txt"
+	}
+	save.SetFileName("flashdown." + extension)
+	save.Show()
+}
+
+func (s *SettingsScreen) exportButton(app Application) *widget.Button {
+	labels := map[string]string{
+		flashdown.FormatAnkiText: "Anki text (HTML)",
+		flashdown.FormatApkg:     "Anki package (.apkg)",
+		flashdown.FormatCSV:      "CSV (Markdown)",
+		flashdown.FormatTSV:      "TSV (Markdown)",
+	}
+	return widget.NewButton("Export", func() {
+		options := make([]string, len(flashdown.ExportFormats))
+		for i, format := range flashdown.ExportFormats {
+			options[i] = labels[format]
+		}
+		formats := widget.NewRadioGroup(options, nil)
+		formats.SetSelected(options[0])
+		dialog.ShowCustomConfirm("Export decks", "Export", "Cancel", formats,
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				for i, option := range options {
+					if option == formats.Selected {
+						s.exportDecks(app, flashdown.ExportFormats[i])
+					}
+				}
+			}, app.Window())
+	})
+}
+
+func (s *SettingsScreen) selectLearning(app Application) *widget.Select {
+	selections := []string{
+		"Failed cards: next session",
+		"Failed cards: again after 3 cards",
+		"Failed cards: again after 1 and 10 min",
+	}
+	values := []string{learningOff, learningGap, learningSteps}
+	onChange := func(selected string) {
+		for i, s := range selections {
+			if s == selected {
+				setLearning(values[i])
+				return
+			}
+		}
+	}
+	learning := widget.NewSelect(selections, onChange)
+	learning.Alignment = fyne.TextAlignCenter
+	mode := getLearning()
+	for i, v := range values {
+		if v == mode {
+			learning.SetSelected(selections[i])
+			break
+		}
+	}
+	return learning
+}
+
+func (s *SettingsScreen) selectOrder(app Application) *widget.Select {
+	selections := []string{
+		"Order: random",
+		"Order: most overdue first",
+		"Order: most likely forgotten first",
+		"Order: one card of each deck in turn",
+	}
+	values := []string{
+		flashdown.OrderRandom,
+		flashdown.OrderOverdue,
+		flashdown.OrderRetrievability,
+		flashdown.OrderRoundRobin,
+	}
+	onChange := func(selected string) {
+		for i, s := range selections {
+			if s == selected {
+				setOrder(values[i])
+				return
+			}
+		}
+	}
+	order := widget.NewSelect(selections, onChange)
+	order.Alignment = fyne.TextAlignCenter
+	current := getOrder()
+	for i, v := range values {
+		if v == current {
+			order.SetSelected(selections[i])
+			break
+		}
+	}
+	return order
+}
+
+func (s *SettingsScreen) selectLeeches(app Application) *widget.Select {
+	selections := []string{
+		"Leeches: keep asking them",
+		"Leeches: suspend them",
+	}
+	onChange := func(selected string) {
+		setSuspendLeeches(selected == selections[1])
+	}
+	leeches := widget.NewSelect(selections, onChange)
+	leeches.Alignment = fyne.TextAlignCenter
+	if getSuspendLeeches() {
+		leeches.SetSelected(selections[1])
+	} else {
+		leeches.SetSelected(selections[0])
+	}
+	return leeches
+}
+
+func (s *SettingsScreen) selectDueDates(app Application) *widget.Select {
+	selections := []string{
+		"Due dates: exact",
+		"Due dates: spread over a few days",
+		"Due dates: least busy of a few days",
+	}
+	values := []string{dueDatesExact, dueDatesFuzz, dueDatesBalance}
+	onChange := func(selected string) {
+		for i, s := range selections {
+			if s == selected {
+				setDueDates(values[i])
+				return
+			}
+		}
+	}
+	dueDates := widget.NewSelect(selections, onChange)
+	dueDates.Alignment = fyne.TextAlignCenter
+	mode := getDueDates()
+	for i, v := range values {
+		if v == mode {
+			dueDates.SetSelected(selections[i])
+			break
+		}
+	}
+	return dueDates
+}
+
+// selectDailyLimit returns a selection of the number of cards studied each
+// day, 0 meaning no limit.
+func (s *SettingsScreen) selectDailyLimit(name string, values []int,
+	get func() int, set func(int)) *widget.Select {
+	selections := make([]string, len(values))
+	for i, v := range values {
+		if v == 0 {
+			selections[i] = fmt.Sprintf("%s per day: no limit", name)
+		} else {
+			selections[i] = fmt.Sprintf("%s per day: %d", name, v)
+		}
+	}
+	onChange := func(selected string) {
+		for i, s := range selections {
+			if s == selected {
+				set(values[i])
+				return
+			}
+		}
+	}
+	limit := widget.NewSelect(selections, onChange)
+	limit.Alignment = fyne.TextAlignCenter
+	current := get()
+	for i, v := range values {
+		if v == current {
+			limit.SetSelected(selections[i])
+			break
+		}
+	}
+	return limit
+}
+
 func (s *SettingsScreen) switchThemeButton(app Application) *widget.Button {
 	currentTheme := getThemeName()
 	var newTheme string
@@ -147,8 +410,19 @@ func (s *SettingsScreen) Show(app Application) {
 	} else {
 		objects = append(objects, s.changeDirectoryButton(app))
 	}
+	objects = append(objects, s.importCSVButton(app))
+	objects = append(objects, s.exportButton(app))
 	objects = append(objects, s.switchThemeButton(app))
 	objects = append(objects, s.selectRepetition(app))
+	objects = append(objects, s.selectScheduler(app))
+	objects = append(objects, s.selectLearning(app))
+	objects = append(objects, s.selectOrder(app))
+	objects = append(objects, s.selectLeeches(app))
+	objects = append(objects, s.selectDueDates(app))
+	objects = append(objects, s.selectDailyLimit("New cards",
+		[]int{0, 5, 10, 20, 50, 100}, getNewCardsPerDay, setNewCardsPerDay))
+	objects = append(objects, s.selectDailyLimit("Reviews",
+		[]int{0, 50, 100, 200, 500}, getReviewsPerDay, setReviewsPerDay))
 	center := container.NewVScroll(container.New(layout.NewVBoxLayout(),
 		objects...))
 	window.SetContent(container.New(layout.NewBorderLayout(
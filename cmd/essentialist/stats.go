@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+const (
+	forecastDays = 30
+	activityDays = 26 * 7 // about 6 months
+)
+
+var (
+	easinessBounds = []float64{1.5, 1.8, 2.1, 2.4, 2.7, 3.0}
+	easinessLabels = []string{"<1.5", "1.5", "1.8", "2.1", "2.4", "2.7", "3.0+"}
+	intervalBounds = []float64{1, 7, 21, 60, 180, 365}
+	intervalLabels = []string{"<1d", "1d", "1w", "3w", "2m", "6m", "1y+"}
+)
+
+// barChartLayout sizes each object as a vertical bar proportional to its
+// value. Bars are aligned on the bottom.
+type barChartLayout struct {
+	values []int
+	height float32
+}
+
+func (b *barChartLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	max := 1
+	for _, v := range b.values {
+		if v > max {
+			max = v
+		}
+	}
+	width := size.Width / float32(len(objects))
+	for i, bar := range objects {
+		height := size.Height * float32(b.values[i]) / float32(max)
+		bar.Resize(fyne.NewSize(width*0.8, height))
+		bar.Move(fyne.NewPos(width*float32(i)+width*0.1, size.Height-height))
+	}
+}
+
+func (b *barChartLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	return fyne.NewSize(float32(len(objects))*4, b.height)
+}
+
+// barChart draws a bar chart with a label below each bar when labels are
+// given.
+func barChart(values []int, labels []string) fyne.CanvasObject {
+	bars := make([]fyne.CanvasObject, len(values))
+	for i := range values {
+		bars[i] = canvas.NewRectangle(theme.Color(theme.ColorNamePrimary))
+	}
+	chart := container.New(&barChartLayout{values, 100}, bars...)
+	if labels == nil {
+		return chart
+	}
+	texts := make([]fyne.CanvasObject, len(labels))
+	for i, label := range labels {
+		text := canvas.NewText(label, theme.Color(theme.ColorNameForeground))
+		text.Alignment = fyne.TextAlignCenter
+		text.TextSize = theme.CaptionTextSize()
+		texts[i] = text
+	}
+	return container.New(layout.NewVBoxLayout(), chart,
+		container.New(layout.NewGridLayout(len(texts)), texts...))
+}
+
+// heatmap draws one square per day, one column per week, the oldest day
+// first. Darker squares represent more reviews.
+func heatmap(activity []int) fyne.CanvasObject {
+	max := 1
+	for _, v := range activity {
+		if v > max {
+			max = v
+		}
+	}
+	r, g, b, _ := theme.Color(theme.ColorNamePrimary).RGBA()
+	squares := make([]fyne.CanvasObject, len(activity))
+	for i, v := range activity {
+		var c color.Color = theme.Color(theme.ColorNameDisabledButton)
+		if v != 0 {
+			alpha := 0.25 + 0.75*float64(v)/float64(max)
+			c = color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8),
+				uint8(alpha * 0xff)}
+		}
+		square := canvas.NewRectangle(c)
+		square.SetMinSize(fyne.NewSize(8, 8))
+		squares[i] = square
+	}
+	return container.New(layout.NewGridLayoutWithRows(7), squares...)
+}
+
+// retentionBars shows the retention rate of each deck.
+func retentionBars(stats []flashdown.DeckStats) fyne.CanvasObject {
+	rows := make([]fyne.CanvasObject, 0, 2*len(stats))
+	for _, s := range stats {
+		bar := widget.NewProgressBar()
+		bar.SetValue(s.Retention)
+		bar.TextFormatter = func() string {
+			if s.Reviews == 0 {
+				return "no review"
+			}
+			return fmt.Sprintf("%.0f%% of %d reviews", s.Retention*100, s.Reviews)
+		}
+		rows = append(rows, widget.NewLabel(s.Name), bar)
+	}
+	return container.New(layout.NewFormLayout(), rows...)
+}
+
+type StatsScreen struct {
+	decks []*flashdown.Deck
+}
+
+func NewStatsScreen(decks []*flashdown.Deck) Screen {
+	return &StatsScreen{decks: decks}
+}
+
+func (s *StatsScreen) charts() (fyne.CanvasObject, error) {
+	now := time.Now()
+	stats := make([]flashdown.DeckStats, len(s.decks))
+	logs := make([]flashdown.ReviewLog, 0)
+	easiness := make([]float64, 0)
+	intervals := make([]float64, 0)
+	for i, deck := range s.decks {
+		var err error
+		stats[i], err = flashdown.NewDeckStats(deck, now)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read history of %s: %s",
+				deck.Name, err)
+		}
+		history, err := deck.History()
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, history...)
+		for _, card := range deck.Cards {
+			easiness = append(easiness, float64(card.Meta.Easiness))
+			intervals = append(intervals, card.Meta.Interval())
+		}
+	}
+	total := flashdown.TotalStats("Total", stats...)
+
+	title := func(text string) fyne.CanvasObject {
+		return widget.NewLabelWithStyle(text, fyne.TextAlignLeading,
+			fyne.TextStyle{Bold: true})
+	}
+	summary := fmt.Sprintf("%d cards: %d new, %d learning, %d mature — %d due today",
+		total.Cards, total.New, total.Learning, total.Mature, total.DueToday)
+	objects := []fyne.CanvasObject{
+		widget.NewLabel(summary),
+		title(fmt.Sprintf("Forecast (next %d days)", forecastDays)),
+		barChart(flashdown.Forecast(s.decks, now, forecastDays), nil),
+		title("Reviews (last 6 months)"),
+		container.NewCenter(heatmap(flashdown.ReviewsPerDay(logs, now, activityDays))),
+		title("Easiness"),
+		barChart(flashdown.Histogram(easiness, easinessBounds), easinessLabels),
+		title("Intervals"),
+		barChart(flashdown.Histogram(intervals, intervalBounds), intervalLabels),
+		title("Retention"),
+		retentionBars(append(stats, total)),
+	}
+	return container.New(layout.NewVBoxLayout(), objects...), nil
+}
+
+func (s *StatsScreen) Show(app Application) {
+	window := app.Window()
+	home := widget.NewButton("Home", func() {
+		app.Display(NewSplashScreen())
+	})
+	leeches := widget.NewButton("Leeches", func() {
+		app.Display(NewLeechesScreen(s.decks))
+	})
+	suspended := widget.NewButton("Suspended", func() {
+		app.Display(NewSuspendedScreen(s.decks))
+	})
+	topBar := newTopBar("Statistics", leeches, suspended, home)
+	charts, err := s.charts()
+	if err != nil {
+		app.Display(NewErrorScreen(err))
+		return
+	}
+	center := container.NewVScroll(container.New(NewMaxWidthCenterLayout(640), charts))
+	window.SetContent(container.New(layout.NewBorderLayout(
+		topBar, nil, nil, nil), topBar, center))
+	window.Canvas().SetOnTypedKey(EscapeKeyHandler(app))
+}
+
+func (s *StatsScreen) Hide(app Application) {
+	app.Window().Canvas().SetOnTypedKey(nil)
+}
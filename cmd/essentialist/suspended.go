@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// SuspendedScreen lists the suspended and the buried cards so that they can
+// be asked again.
+type SuspendedScreen struct {
+	decks []*flashdown.Deck
+}
+
+func NewSuspendedScreen(decks []*flashdown.Deck) Screen {
+	return &SuspendedScreen{decks: decks}
+}
+
+// unsuspend asks the card again and saves the progress of its deck.
+func (s *SuspendedScreen) unsuspend(app Application, deck *flashdown.Deck,
+	card flashdown.Card) {
+	card.Meta.Unsuspend()
+	if err := deck.SaveDeckMeta(); err != nil {
+		app.Display(NewErrorScreen(err))
+		return
+	}
+	app.Display(s)
+}
+
+func (s *SuspendedScreen) cards(app Application) fyne.CanvasObject {
+	now := time.Now()
+	objects := make([]fyne.CanvasObject, 0)
+	for _, deck := range s.decks {
+		for _, card := range deck.HiddenCards(now) {
+			state := "suspended"
+			if !card.Meta.Suspended {
+				state = "buried until tomorrow"
+			}
+			question := widget.NewLabelWithStyle(card.Question,
+				fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			question.Wrapping = fyne.TextWrapWord
+			location := widget.NewLabel(fmt.Sprintf("%s, line %d — %s",
+				deck.Title(), card.Line, state))
+			location.Wrapping = fyne.TextWrapWord
+			button := widget.NewButton("Unsuspend", func() {
+				s.unsuspend(app, deck, card)
+			})
+			objects = append(objects, widget.NewSeparator(), question,
+				container.NewBorder(nil, nil, nil, button, location))
+		}
+	}
+	if len(objects) == 0 {
+		return container.NewCenter(widget.NewLabel("No card suspended or buried."))
+	}
+	return container.New(layout.NewVBoxLayout(), objects...)
+}
+
+func (s *SuspendedScreen) Show(app Application) {
+	window := app.Window()
+	stats := widget.NewButton("Stats", func() {
+		app.Display(NewStatsScreen(s.decks))
+	})
+	home := widget.NewButton("Home", func() {
+		app.Display(NewSplashScreen())
+	})
+	topBar := newTopBar("Suspended cards", stats, home)
+	center := container.NewVScroll(container.New(NewMaxWidthCenterLayout(640),
+		s.cards(app)))
+	window.SetContent(container.New(layout.NewBorderLayout(
+		topBar, nil, nil, nil), topBar, center))
+	window.Canvas().SetOnTypedKey(EscapeKeyHandler(app))
+}
+
+func (s *SuspendedScreen) Hide(app Application) {
+	app.Window().Canvas().SetOnTypedKey(nil)
+}
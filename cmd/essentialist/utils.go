@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"image/color"
 	"log"
@@ -8,6 +9,7 @@ import (
 	"path"
 	"strings"
 	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -25,6 +27,28 @@ const (
 	cardsNbEntry   = "number of cards per session"
 	directoryEntry = "directory"
 	themeEntry     = "theme"
+	schedulerEntry = "scheduler"
+	learningEntry  = "learning"
+	sessionEntry   = "session"
+	newCardsEntry  = "new cards per day"
+	reviewsEntry   = "reviews per day"
+	orderEntry     = "order"
+	leechesEntry   = "suspend leeches"
+	dueDatesEntry  = "due dates"
+)
+
+// Learning modes of the sessions.
+const (
+	learningOff   = "off"   // failed cards are asked in the next session
+	learningGap   = "gap"   // failed cards are asked again after a few cards
+	learningSteps = "steps" // failed cards are asked again after 1 and 10 minutes
+)
+
+// Due dates of the reviewed cards.
+const (
+	dueDatesExact   = "exact"   // as computed by the scheduler
+	dueDatesFuzz    = "fuzz"    // moved by a few days randomly
+	dueDatesBalance = "balance" // moved to the day with the fewest cards due
 )
 
 // overrideDirectory is used to specify a directory as an argument.
@@ -82,6 +106,148 @@ func setRepetitionLenght(nbCards int) {
 	prefs.SetInt(cardsNbEntry, nbCards)
 }
 
+func getScheduler() flashdown.Scheduler {
+	prefs := fyne.CurrentApp().Preferences()
+	name := prefs.StringWithFallback(schedulerEntry, "sm2")
+	scheduler, err := flashdown.NewScheduler(name)
+	if err != nil {
+		return flashdown.NewSM2Scheduler()
+	}
+	return scheduler
+}
+
+func setScheduler(scheduler flashdown.Scheduler) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetString(schedulerEntry, scheduler.Name())
+	flashdown.DefaultScheduler = scheduler
+}
+
+func getLearning() string {
+	prefs := fyne.CurrentApp().Preferences()
+	return prefs.StringWithFallback(learningEntry, learningOff)
+}
+
+func setLearning(mode string) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetString(learningEntry, mode)
+}
+
+// getNewCardsPerDay returns the maximum number of new cards studied each
+// day, 0 if there is no limit.
+func getNewCardsPerDay() int {
+	prefs := fyne.CurrentApp().Preferences()
+	return prefs.IntWithFallback(newCardsEntry, 0)
+}
+
+func setNewCardsPerDay(limit int) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetInt(newCardsEntry, limit)
+}
+
+// getReviewsPerDay returns the maximum number of cards reviewed each day, 0
+// if there is no limit.
+func getReviewsPerDay() int {
+	prefs := fyne.CurrentApp().Preferences()
+	return prefs.IntWithFallback(reviewsEntry, 0)
+}
+
+func setReviewsPerDay(limit int) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetInt(reviewsEntry, limit)
+}
+
+func getOrder() string {
+	prefs := fyne.CurrentApp().Preferences()
+	return prefs.StringWithFallback(orderEntry, flashdown.OrderRandom)
+}
+
+func setOrder(order string) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetString(orderEntry, order)
+}
+
+func getSuspendLeeches() bool {
+	prefs := fyne.CurrentApp().Preferences()
+	return prefs.BoolWithFallback(leechesEntry, false)
+}
+
+func setSuspendLeeches(suspend bool) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetBool(leechesEntry, suspend)
+}
+
+func getDueDates() string {
+	prefs := fyne.CurrentApp().Preferences()
+	return prefs.StringWithFallback(dueDatesEntry, dueDatesFuzz)
+}
+
+func setDueDates(mode string) {
+	prefs := fyne.CurrentApp().Preferences()
+	prefs.SetString(dueDatesEntry, mode)
+}
+
+// sessionOptions returns the options of a session from the settings.
+func sessionOptions(cardsNb int) flashdown.GameOptions {
+	options := flashdown.GameOptions{
+		CardsNb:        cardsNb,
+		NewCardsPerDay: getNewCardsPerDay(),
+		ReviewsPerDay:  getReviewsPerDay(),
+		Order:          getOrder(),
+		SuspendLeeches: getSuspendLeeches(),
+	}
+	switch getDueDates() {
+	case dueDatesFuzz:
+		options.Fuzz = true
+	case dueDatesBalance:
+		options.Fuzz = true
+		options.LoadBalance = true
+	}
+	switch getLearning() {
+	case learningGap:
+		options.Learning = true
+	case learningSteps:
+		options.Learning = true
+		options.LearningSteps = []time.Duration{time.Minute, 10 * time.Minute}
+	}
+	return options
+}
+
+// saveGame saves the progress of the game and remembers the session to
+// resume it later, unless it is finished.
+func saveGame(game *flashdown.Game) {
+	game.Save()
+	prefs := fyne.CurrentApp().Preferences()
+	if game.IsFinished() {
+		prefs.RemoveValue(sessionEntry)
+		return
+	}
+	var buf bytes.Buffer
+	if err := flashdown.WriteSession(&buf, game.Session()); err != nil {
+		log.Printf("Cannot save session: %s", err)
+		return
+	}
+	prefs.SetString(sessionEntry, buf.String())
+}
+
+// getSession returns the session to resume, nil if there is none.
+func getSession() *flashdown.Session {
+	prefs := fyne.CurrentApp().Preferences()
+	data := prefs.String(sessionEntry)
+	if data == "" {
+		return nil
+	}
+	session, err := flashdown.ReadSession(strings.NewReader(data))
+	if err != nil || session.Remaining() <= 0 {
+		prefs.RemoveValue(sessionEntry)
+		return nil
+	}
+	return session
+}
+
+func clearSession() {
+	fyne.CurrentApp().Preferences().RemoveValue(sessionEntry)
+}
+
 // getDirectory return the location where to look for decks. Set
 // overrideDirectory to select which directory is returned by getDirectory. If
 // overrideDirectory is unset, getDirectory returns the dirextory from the
@@ -141,13 +307,26 @@ func newHomeTopBar(app Application, s *HomeScreen) *fyne.Container {
 	start := widget.NewButton("Start", func() {
 		s.startQuickSession(app)
 	})
+	stats := widget.NewButton("Stats", func() {
+		s.showStats(app)
+	})
+	tags := widget.NewButton("Tags", func() {
+		s.selectTags(app)
+	})
 	help := widget.NewButton("Help", func() {
 		app.Display(NewHelpScreen())
 	})
 	quit := widget.NewButton("Quit", func() {
 		app.Window().Close()
 	})
-	return newTopBar("Home", start, help, settings, quit)
+	if session := getSession(); session != nil {
+		resume := widget.NewButton("Resume", func() {
+			s.resumeSession(app)
+		})
+		return newTopBar("Home", resume, start, tags, stats, help,
+			settings, quit)
+	}
+	return newTopBar("Home", start, tags, stats, help, settings, quit)
 }
 
 func newProgressTopBar(app Application, game *flashdown.Game) *fyne.Container {
@@ -156,7 +335,7 @@ func newProgressTopBar(app Application, game *flashdown.Game) *fyne.Container {
 	text := fmt.Sprintf("Session: %d/%d — Success: %.0f%% — %s",
 		current, total, percent, game.DeckName())
 	home := widget.NewButton("Home", func() {
-		game.Save()
+		saveGame(game)
 		app.Display(NewSplashScreen())
 	})
 	return newTopBar(text, home)
@@ -233,6 +412,32 @@ func importFile(source fyne.URI) error {
 		source.String(), destination.String(), err)
 }
 
+// importCSV converts a CSV or TSV file into a deck of the storage directory.
+func importCSV(source fyne.URIReadCloser) (*flashdown.CSVImport, error) {
+	options := flashdown.CSVOptions{}
+	if source.URI().Extension() == ".tsv" {
+		options.Comma = '\t'
+	}
+	decoded, err := url.PathUnescape(source.URI().Name())
+	if err != nil {
+		decoded = source.URI().Name()
+	}
+	filename := strings.TrimSuffix(path.Base(decoded), path.Ext(decoded)) + ".md"
+	destination, err := storage.Child(getDirectory(), filename)
+	if err != nil {
+		return nil, err
+	}
+	if exists, _ := storage.Exists(destination); exists {
+		return nil, fmt.Errorf("%s already exists", filename)
+	}
+	var md bytes.Buffer
+	result, err := flashdown.ImportCSV(source, &md, options)
+	if err != nil {
+		return nil, err
+	}
+	return result, writeFile(destination, md.Bytes())
+}
+
 func importDirectory(directory fyne.ListableURI) error {
 	files, err := directory.List()
 	if err != nil {
@@ -261,6 +466,13 @@ func dbFile(file fyne.URI) (fyne.URI, error) {
 	return storage.ParseURI(uri)
 }
 
+func historyFile(file fyne.URI) (fyne.URI, error) {
+	uri := file.String()
+	base := path.Base(uri)
+	uri = strings.Replace(uri, base, "."+base+".log", 1)
+	return storage.ParseURI(uri)
+}
+
 func loadDecks() ([]flashdown.DeckAccessor, error) {
 	return loadDir(getDirectory())
 }
@@ -300,7 +512,12 @@ func loadDir(dir fyne.URI) ([]flashdown.DeckAccessor, error) {
 				errors <- fmt.Errorf("Failed to create URI: %s", err)
 				return
 			}
-			results <- NewDeckAccessor(file, db)
+			history, err := historyFile(file)
+			if err != nil {
+				errors <- fmt.Errorf("Failed to create URI: %s", err)
+				return
+			}
+			results <- NewDeckAccessor(file, db, history)
 		}(file)
 	}
 
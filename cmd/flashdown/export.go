@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// exportCommand writes the decks in a format other programs can import.
+func exportCommand(args []string) {
+	format := ""
+	output := ""
+	files := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-f", "--format", "-format":
+			if i+1 < len(args) {
+				i++
+				format = args[i]
+			}
+		case "-o", "--output", "-output":
+			if i+1 >= len(args) {
+				fmt.Print("Argument -o must be followed by a file name.\n")
+				os.Exit(1)
+			}
+			i++
+			output = args[i]
+		default:
+			files = append(files, deckFiles(args[i])...)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	if !isExportFormat(format) {
+		fmt.Printf("Argument --format must be followed by one of: %s.\n",
+			strings.Join(flashdown.ExportFormats, ", "))
+		os.Exit(1)
+	}
+	decks, err := flashdown.NewDecksFromFiles(files)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var w io.WriteCloser = os.Stdout
+	if output != "" {
+		if w, err = os.Create(output); err != nil {
+			fmt.Printf("Cannot create %s: %s.\n", output, err)
+			os.Exit(1)
+		}
+	}
+	if err := flashdown.Export(w, format, decks...); err != nil {
+		fmt.Printf("Cannot export the decks: %s.\n", err)
+		os.Exit(1)
+	}
+	if err := w.Close(); err != nil {
+		fmt.Printf("Cannot write %s: %s.\n", output, err)
+		os.Exit(1)
+	}
+}
+
+func isExportFormat(format string) bool {
+	for _, f := range flashdown.ExportFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
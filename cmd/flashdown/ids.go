@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// idsCommand adds an identifier to the cards of the decks so that their
+// progress survives the edition of their question.
+func idsCommand(args []string) {
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		files = append(files, deckFiles(arg)...)
+	}
+	if len(files) == 0 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			fmt.Printf("Cannot access %s: %s.\n", file, err)
+			os.Exit(1)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Printf("Cannot read %s: %s.\n", file, err)
+			os.Exit(1)
+		}
+		md, added, err := flashdown.InsertIDs(string(data))
+		if err != nil {
+			fmt.Printf("Cannot parse %s: %s.\n", file, err)
+			os.Exit(1)
+		}
+		if added == 0 {
+			continue
+		}
+		if err := os.WriteFile(file, []byte(md), info.Mode()); err != nil {
+			fmt.Printf("Cannot write %s: %s.\n", file, err)
+			os.Exit(1)
+		}
+		// Save the progress under the new digests.
+		deck, err := flashdown.NewDeckFromFile(file)
+		if err == nil {
+			err = deck.SaveDeckMeta()
+		}
+		if err != nil {
+			fmt.Printf("Cannot update the progress of %s: %s.\n", file, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: %d ids added.\n", file, added)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// importCommand converts decks from other programs into Markdown decks.
+func importCommand(args []string) {
+	if len(args) < 2 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	switch args[0] {
+	case "anki":
+		importAnki(args[1:])
+	case "csv", "tsv":
+		importCSV(args[0], args[1:])
+	default:
+		fmt.Printf("Unknown format: %s.\n", args[0])
+		os.Exit(1)
+	}
+}
+
+func importAnki(args []string) {
+	if len(args) > 2 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	dir := "."
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	result, err := flashdown.ImportAnki(args[0], dir)
+	if err != nil {
+		fmt.Printf("Cannot import %s: %s.\n", args[0], err)
+		os.Exit(1)
+	}
+	for _, file := range result.Files {
+		fmt.Printf("%s created.\n", file)
+	}
+	fmt.Printf("%d notes imported, %d skipped, %d media files copied.\n",
+		result.Notes, result.Skipped, result.Media)
+}
+
+func importCSV(format string, args []string) {
+	options := flashdown.CSVOptions{}
+	if format == "tsv" {
+		options.Comma = '\t'
+	}
+	files := make([]string, 0, 2)
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-d", "--delimiter", "-delimiter":
+			options.Comma = utf8.RuneError
+			if i+1 < len(args) {
+				i++
+				options.Comma = delimiter(args[i])
+			}
+			if options.Comma == utf8.RuneError {
+				fmt.Print("Argument --delimiter must be followed by a character or tab.\n")
+				os.Exit(1)
+			}
+		case "--columns", "-columns":
+			if i+1 >= len(args) {
+				fmt.Print("Argument --columns must be followed by a list like question,answer,tags.\n")
+				os.Exit(1)
+			}
+			i++
+			options.Columns = strings.Split(args[i], ",")
+			for j, column := range options.Columns {
+				if column == "-" {
+					options.Columns[j] = flashdown.ColumnIgnored
+				}
+			}
+		case "--header", "-header":
+			options.Header = flashdown.HeaderPresent
+		case "--no-header", "-no-header":
+			options.Header = flashdown.HeaderAbsent
+		case "--title", "-title":
+			if i+1 >= len(args) {
+				fmt.Print("Argument --title must be followed by a title.\n")
+				os.Exit(1)
+			}
+			i++
+			options.Title = args[i]
+		case "--markdown", "-markdown":
+			options.Markdown = true
+		default:
+			files = append(files, args[i])
+		}
+	}
+	if len(files) == 0 || len(files) > 2 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	dir := "."
+	if len(files) == 2 {
+		dir = files[1]
+	}
+	result, err := flashdown.ImportCSVFile(files[0], dir, options)
+	if err != nil {
+		fmt.Printf("Cannot import %s: %s.\n", files[0], err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s created.\n", result.File)
+	fmt.Printf("%d notes imported, %d skipped.\n", result.Notes, result.Skipped)
+}
+
+// delimiter returns the delimiter described by s, utf8.RuneError if invalid.
+func delimiter(s string) rune {
+	if s == "tab" || s == `\t` {
+		return '\t'
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) {
+		return utf8.RuneError
+	}
+	return r
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+func leechesCommand(args []string) {
+	asJSON := false
+	threshold := 0
+	files := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-j", "--json", "-json":
+			asJSON = true
+		case "--threshold", "-threshold":
+			var err error
+			if i+1 < len(args) {
+				i++
+				threshold, err = strconv.Atoi(args[i])
+			}
+			if threshold <= 0 || err != nil {
+				fmt.Print("Argument --threshold must be followed by a positive number.\n")
+				os.Exit(1)
+			}
+		default:
+			files = append(files, deckFiles(args[i])...)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	decks, err := flashdown.NewDecksFromFiles(files)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// The leeches are reported with the path of their deck.
+	leeches := make([]flashdown.Leech, 0)
+	for i, deck := range decks {
+		for _, leech := range flashdown.Leeches(threshold, deck) {
+			leech.Deck = files[i]
+			leeches = append(leeches, leech)
+		}
+	}
+	sort.SliceStable(leeches, func(i, j int) bool {
+		return leeches[i].Lapses > leeches[j].Lapses
+	})
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(leeches); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	for _, leech := range leeches {
+		suspended := ""
+		if leech.Suspended {
+			suspended = ", suspended"
+		}
+		fmt.Printf("%s:%d: %s (%d lapses%s)\n", leech.Deck, leech.Line,
+			leech.Question, leech.Lapses, suspended)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// lintCommand reports the issues of the decks. It exits with status 1 if an
+// issue is found.
+func lintCommand(args []string) {
+	asJSON := false
+	options := flashdown.LintOptions{}
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "-j", "--json", "-json":
+			asJSON = true
+		case "-l", "--links", "-links":
+			options.CheckLink = flashdown.CheckURL
+		default:
+			files = append(files, deckFiles(arg)...)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	issues := make([]flashdown.Issue, 0)
+	for _, file := range files {
+		deckIssues, err := flashdown.LintFile(file, options)
+		if err != nil {
+			fmt.Printf("Cannot read %s: %s.\n", file, err)
+			os.Exit(1)
+		}
+		issues = append(issues, deckIssues...)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(issues); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+	if len(issues) != 0 {
+		os.Exit(1)
+	}
+}
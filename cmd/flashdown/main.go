@@ -1,12 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
@@ -14,16 +19,64 @@ import (
 )
 
 const (
-	helpQuestion = `Press space to continue, 's' to skip or 'q' to quit`
+	helpQuestion = `Press space to continue, 's' to skip, 'b' to bury, '!' to suspend, 'u' to undo or 'q' to quit`
 
 	usageMsg = `Spaced repetition program for flashcards in Markdown.
 
-Usage: %s [-a] [-n <number of cards>] <file or directory> [<file> ...]
+Usage: %[1]s [-a] [-n <number of cards>] [-s <sm2|fsrs>] [-t <tag>] [-l <gap|steps>] [--new <n>] [--reviews <n>] [--order <order>] [--suspend-leeches] [--balance|--no-fuzz] <file or directory> [<file> ...]
+       %[1]s stats [--json] <file or directory> [<file> ...]
+       %[1]s ids <file or directory> [<file> ...]
+       %[1]s lint [--json] [--links] <file or directory> [<file> ...]
+       %[1]s leeches [--json] [--threshold <n>] <file or directory> [<file> ...]
+       %[1]s suspended <file or directory> [<file> ...]
+       %[1]s unsuspend <file or directory>[:<line>] [<file>[:<line>] ...]
+       %[1]s recover <file or directory> [<file> ...]
+       %[1]s import anki <file.apkg> [<directory>]
+       %[1]s import <csv|tsv> [-d <delimiter>] [--columns <list>] [--header|--no-header] [--title <title>] [--markdown] <file> [<directory>]
+       %[1]s export --format <anki|csv|tsv|apkg> [-o <file>] <file or directory> [<file> ...]
+
 Flags:
-	-a | --all    : force all cards in the deck to be used.
-	-h | --help   : show this message.
-	-n | --number : set the number of cards used.
-	-d | --debug  : debug logs are written to a temprorary file.
+	-a | --all        : force all cards in the deck to be used.
+	-h | --help       : show this message.
+	-n | --number     : set the number of cards used.
+	-s | --scheduler  : set the algorithm used to schedule the cards (sm2 or fsrs).
+	-t | --tag        : only use the cards with this tag (can be repeated).
+	-l | --learn      : ask the failed cards again until they are answered correctly,
+	                    after a number of cards (ex: 3) or after delays (ex: 1m,10m).
+	--new <n>         : set the maximum number of new cards studied per day.
+	--reviews <n>     : set the maximum number of cards reviewed per day.
+	--order <order>   : select which cards are asked first: random, overdue (most
+	                    overdue), retrievability (most likely forgotten) or
+	                    round-robin (one card of each deck in turn).
+	--suspend-leeches : stop asking the cards failed too often (see leeches).
+	--balance         : move the due dates of the reviewed cards to the day with the
+	                    fewest cards due, within a few days, instead of a random day.
+	--no-fuzz         : keep the due dates computed by the scheduler.
+	-d | --debug      : debug logs are written to a temprorary file.
+	--now <date>      : preview the cards due on a date (YYYY-MM-DD), progress isn't saved.
+
+Commands:
+	stats     : print the statistics of the decks (--json for a machine readable output).
+	ids       : add an identifier to the cards so that their progress survives question edits.
+	lint      : report the issues of the decks as file:line:column (--json for a
+	            machine readable output, --links to check the links).
+	leeches   : list the cards failed too often once learned, the most failed
+	            first, to rewrite them. A card is a leech after 8 lapses unless
+	            --threshold or the leech_threshold setting of its deck is given.
+	suspended : list the cards suspended ('!' during a session) or buried until
+	            the next day ('b' during a session).
+	unsuspend : ask again the suspended and buried cards of the decks, or only
+	            the card whose question is at the given line.
+	recover   : restore the backup of corrupted progress files.
+	import    : convert an Anki package into Markdown decks, with their media
+	            files and their progress, or convert a CSV/TSV file (ex: a Quizlet
+	            export) into a deck. The delimiter and the header are detected
+	            unless given. --columns gives the role of each column, like
+	            answer,question,-,tags (- ignores a column). --markdown keeps
+	            the two-sided cards and the cloze deletions of the questions.
+	export    : write the decks as an Anki text file (anki), as CSV or TSV with
+	            Markdown fields, or as an Anki package with the images (apkg).
+	            The output is written to stdout unless -o is given.
 
 A deck is a plain text Markdown file where questions have heading level 1 like:
 
@@ -41,8 +94,8 @@ A deck is a plain text Markdown file where questions have heading level 1 like:
 
 var (
 	helpAnswers = []string{
-		` Press [0-5] to continue, 's' to skip, 'q' to quit, 'h' for help`,
-		` Press [0-5] to continue, 's' to skip, 'q' to quit, 'h' for help
+		` Press [0-5] to continue, 's' to skip, 'b' to bury, '!' to suspend, 'u' to undo, 'q' to quit, 'h' for help`,
+		` Press [0-5] to continue, 's' to skip, 'b' to bury, '!' to suspend, 'u' to undo, 'q' to quit, 'h' for help
 
 5: Perfect response
 4: Correct response, after some hesitation
@@ -58,14 +111,122 @@ var (
 	game *flashdown.Game
 )
 
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// discardHistory prevents the reviews made during a preview from being
+// recorded.
+func discardHistory() (io.WriteCloser, error) {
+	return nopWriteCloser{io.Discard}, nil
+}
+
+// isOrder returns true if order is supported by flashdown.OrderCards.
+func isOrder(order string) bool {
+	for _, o := range flashdown.Orders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
+
+// deckFiles returns the markdown files of a deck. If file is a directory,
+// it returns all the markdown files inside the directory.
+func deckFiles(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Printf("Cannot open %s: %s.\n", file, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		fmt.Printf("Cannot access %s: %s.\n", file, err)
+		os.Exit(1)
+	}
+	if !info.IsDir() {
+		return []string{file}
+	}
+	files := make([]string, 0)
+	entries, err := f.ReadDir(-1)
+	if err != nil {
+		fmt.Printf("Cannot list files inside %s.\n", file)
+		os.Exit(1)
+	}
+	for _, entry := range entries {
+		// skip diretories
+		if entry.IsDir() || path.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		filename := path.Join(file, entry.Name())
+		files = append(files, filename)
+	}
+	return files
+}
+
+// parseLearning enables the learning mode with a gap (ex: 3) or with
+// learning steps (ex: 1m,10m).
+func parseLearning(arg string, options *flashdown.GameOptions) error {
+	options.Learning = true
+	if gap, err := strconv.Atoi(arg); err == nil {
+		if gap <= 0 {
+			return fmt.Errorf("invalid gap: %d", gap)
+		}
+		options.LearningGap = gap
+		return nil
+	}
+	for _, step := range strings.Split(arg, ",") {
+		delay, err := time.ParseDuration(step)
+		if err != nil {
+			return err
+		}
+		options.LearningSteps = append(options.LearningSteps, delay)
+	}
+	return nil
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Printf(usageMsg, os.Args[0])
 		os.Exit(1)
 	}
 
+	switch os.Args[1] {
+	case "stats":
+		statsCommand(os.Args[2:])
+		return
+	case "ids":
+		idsCommand(os.Args[2:])
+		return
+	case "lint":
+		lintCommand(os.Args[2:])
+		return
+	case "leeches":
+		leechesCommand(os.Args[2:])
+		return
+	case "suspended":
+		suspendedCommand(os.Args[2:])
+		return
+	case "unsuspend":
+		unsuspendCommand(os.Args[2:])
+		return
+	case "recover":
+		recoverCommand(os.Args[2:])
+		return
+	case "import":
+		importCommand(os.Args[2:])
+		return
+	case "export":
+		exportCommand(os.Args[2:])
+		return
+	}
+
 	cardsNb := flashdown.CARDS_TO_REVIEW
 	files := make([]string, 0, len(os.Args))
+	tags := make([]string, 0)
+	preview := false
+	options := flashdown.GameOptions{Fuzz: true}
 
 	for i := 1; i < len(os.Args); i++ {
 		switch os.Args[i] {
@@ -92,47 +253,133 @@ func main() {
 				os.Exit(1)
 			}
 			continue
-		}
-
-		file := os.Args[i]
-		// If file is a directory. Add all markdown files in the directory.
-		f, err := os.Open(file)
-		if err != nil {
-			fmt.Printf("Cannot open %s: %s.\n", file, err)
-			os.Exit(1)
-		}
-		info, err := f.Stat()
-		if err != nil {
-			fmt.Printf("Cannot access %s: %s.\n", file, err)
-			os.Exit(1)
-		}
-		if info.IsDir() {
-			entries, err := f.ReadDir(-1)
+		case "-s", "--scheduler", "-scheduler":
+			err := fmt.Errorf("missing scheduler")
+			if i+1 < len(os.Args) {
+				i++
+				flashdown.DefaultScheduler, err = flashdown.NewScheduler(os.Args[i])
+			}
 			if err != nil {
-				fmt.Printf("Cannot list files inside %s.\n", file)
+				fmt.Print("Argument -s must be followed by sm2 or fsrs.\n")
 				os.Exit(1)
 			}
-			for _, entry := range entries {
-				// skip diretories
-				if entry.IsDir() || path.Ext(entry.Name()) != ".md" {
-					continue
-				}
-				filename := path.Join(path.Dir(file), path.Base(file), entry.Name())
-				files = append(files, filename)
+			continue
+		case "-t", "--tag", "-tag":
+			if i+1 >= len(os.Args) {
+				fmt.Print("Argument -t must be followed by a tag.\n")
+				os.Exit(1)
+			}
+			i++
+			tags = append(tags, strings.TrimPrefix(os.Args[i], "#"))
+			continue
+		case "-l", "--learn", "-learn":
+			err := fmt.Errorf("missing learning steps")
+			if i+1 < len(os.Args) {
+				i++
+				err = parseLearning(os.Args[i], &options)
+			}
+			if err != nil {
+				fmt.Print("Argument -l must be followed by a number of cards or by delays like 1m,10m.\n")
+				os.Exit(1)
+			}
+			continue
+		case "--new", "-new", "--reviews", "-reviews":
+			flag := os.Args[i]
+			limit, err := -1, fmt.Errorf("missing limit")
+			if i+1 < len(os.Args) {
+				i++
+				limit, err = strconv.Atoi(os.Args[i])
+			}
+			if limit < 0 || err != nil {
+				fmt.Printf("Argument %s must be followed by a number.\n", flag)
+				os.Exit(1)
+			}
+			if strings.HasSuffix(flag, "new") {
+				options.NewCardsPerDay = limit
+			} else {
+				options.ReviewsPerDay = limit
+			}
+			continue
+		case "--order", "-order":
+			if i+1 >= len(os.Args) || !isOrder(os.Args[i+1]) {
+				fmt.Printf("Argument --order must be followed by %s.\n",
+					strings.Join(flashdown.Orders, ", "))
+				os.Exit(1)
+			}
+			i++
+			options.Order = os.Args[i]
+			continue
+		case "--suspend-leeches", "-suspend-leeches":
+			options.SuspendLeeches = true
+			continue
+		case "--balance", "-balance":
+			options.Fuzz, options.LoadBalance = true, true
+			continue
+		case "--no-fuzz", "-no-fuzz":
+			options.Fuzz, options.LoadBalance = false, false
+			continue
+		case "--now", "-now":
+			err := fmt.Errorf("missing date")
+			var now time.Time
+			if i+1 < len(os.Args) {
+				i++
+				now, err = time.ParseInLocation("2006-01-02", os.Args[i], time.Local)
 			}
-		} else {
-			files = append(files, file)
+			if err != nil {
+				fmt.Print("Argument --now must be followed by a date (YYYY-MM-DD).\n")
+				os.Exit(1)
+			}
+			// Preview the cards due at any time of that day.
+			now = now.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			flashdown.DefaultClock = flashdown.NewFixedClock(now)
+			preview = true
+			continue
 		}
+
+		files = append(files, deckFiles(os.Args[i])...)
 	}
 
-	game, err := flashdown.NewGameFromFiles(cardsNb, files)
-	if err != nil {
+	decks, err := flashdown.NewDecksFromFiles(files)
+	if errors.Is(err, flashdown.ErrCorruptedMeta) {
+		fmt.Printf("%s\nRun '%s recover <file>' to restore the last backup.\n",
+			err, os.Args[0])
+		os.Exit(1)
+	} else if err != nil {
 		log.Fatal(err)
 	}
+	if preview {
+		for _, deck := range decks {
+			deck.HistoryWriter = discardHistory
+		}
+	}
+	options.CardsNb = cardsNb
+	options.Clock = flashdown.DefaultClock
+	if len(tags) != 0 {
+		options.Filter = flashdown.TagFilter(tags...)
+	}
+	if !preview {
+		game = resumeSession(files, decks)
+	}
+	if game == nil {
+		game = flashdown.NewGameWithOptions(options, decks...)
+	}
 	if game.IsFinished() {
 		return
 	}
-	defer game.Save()
+	save := func() {
+		if preview {
+			return
+		}
+		game.Save()
+		if err := saveSession(files, game); err != nil {
+			log.Printf("Failed to save the session: %v", err)
+		}
+	}
+	defer save()
+
+	// Save the session when the terminal is closed.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM)
 
 	if err := ui.Init(); err != nil {
 		log.Fatalf("failed to initialize termui: %v", err)
@@ -170,11 +417,14 @@ func main() {
 		q.Title = fmt.Sprintf(`Card: %d/%d — Success %2.0f%%`,
 			current, total, percent)
 		a.Title = fmt.Sprintf(`Deck: %s`, game.DeckName())
+		if description := game.DeckDescription(); description != "" {
+			a.Title += " — " + description
+		}
 	}
 
 	ask := func() {
 		updateTitle()
-		q.Text = game.Question()
+		q.Text = game.MarkedQuestion()
 		a.Text = ""
 		help.Text = helpQuestion
 		ui.Clear()
@@ -183,10 +433,15 @@ func main() {
 	review := func(score flashdown.Score) {
 		game.Review(score)
 		ask()
+		if leech, ok := game.SuspendedLeech(); ok {
+			help.Text = fmt.Sprintf("Leech suspended after %d lapses (line %d of %s). %s",
+				leech.Meta.Lapses, leech.Line, leech.DeckName, helpQuestion)
+			ui.Render(help)
+		}
 	}
 	answer := func() {
-		q.Text = game.Question()
-		a.Text = game.Answer()
+		q.Text = game.MarkedQuestion()
+		a.Text = game.MarkedAnswer()
 		help.Text = helpAnswer
 		ui.Clear()
 		ui.Render(grid, help)
@@ -208,6 +463,8 @@ func main() {
 	uiEvents := ui.PollEvents()
 	for {
 		select {
+		case <-signals:
+			return
 		case e := <-uiEvents:
 			switch e.ID {
 			case "s", "n":
@@ -216,11 +473,25 @@ func main() {
 					return
 				}
 				ask()
+			case "b", "!":
+				if e.ID == "b" {
+					game.Bury()
+				} else {
+					game.Suspend()
+				}
+				if game.IsFinished() {
+					return
+				}
+				ask()
 			case "w":
-				game.Save()
+				save()
 			case "p":
 				game.Previous()
 				ask()
+			case "u":
+				if game.Undo() {
+					answer()
+				}
 			case "q", "<C-c>":
 				return
 			case "h":
@@ -7,6 +7,8 @@ import (
 	. "github.com/gizak/termui/v3"
 
 	markdown "github.com/MichaelMure/go-term-markdown"
+
+	flashdown "github.com/lugu/flashdown/internal"
 )
 
 type MarkdownArea struct {
@@ -82,6 +84,35 @@ func convertAnsi(cells []Cell) []Cell {
 	return cells
 }
 
+// styleClozes removes the marks of the cloze deletions and styles the
+// blanked and the revealed deletions.
+func styleClozes(cells []Cell) []Cell {
+	blank := NewStyle(ColorCyan, ColorClear, ModifierBold)
+	reveal := NewStyle(ColorGreen, ColorClear, ModifierBold)
+
+	i := 0
+	var style *Style
+	for _, x := range cells {
+		switch x.Rune {
+		case flashdown.ClozeBlankStart:
+			style = &blank
+			continue
+		case flashdown.ClozeRevealStart:
+			style = &reveal
+			continue
+		case flashdown.ClozeBlankEnd, flashdown.ClozeRevealEnd:
+			style = nil
+			continue
+		}
+		if style != nil {
+			x.Style = *style
+		}
+		cells[i] = x
+		i++
+	}
+	return cells[:i]
+}
+
 func (self *MarkdownArea) Draw(buf *Buffer) {
 	self.Block.Draw(buf)
 
@@ -91,6 +122,7 @@ func (self *MarkdownArea) Draw(buf *Buffer) {
 
 	cells := ParseStyles(text, self.TextStyle)
 	cells = convertAnsi(cells)
+	cells = styleClozes(cells)
 	cells = WrapCells(cells, uint(self.Inner.Dx()))
 
 	rows := SplitCells(cells, '\n')
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// recoverCommand repairs the corrupted progress files of the decks.
+func recoverCommand(args []string) {
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		files = append(files, deckFiles(arg)...)
+	}
+	if len(files) == 0 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	for _, file := range files {
+		_, err := flashdown.NewDeckFromFile(file)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, flashdown.ErrCorruptedMeta) {
+			fmt.Printf("Cannot load %s: %s.\n", file, err)
+			os.Exit(1)
+		}
+		restored, err := flashdown.RecoverMetaFromFile(file)
+		if err != nil {
+			fmt.Printf("Cannot recover %s: %s.\n", file, err)
+			os.Exit(1)
+		}
+		if restored {
+			fmt.Printf("%s: progress restored from the backup.\n", file)
+		} else {
+			fmt.Printf("%s: no valid backup, progress reset.\n", file)
+		}
+	}
+}
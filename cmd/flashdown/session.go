@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// savedSession is the interrupted session saved on quit.
+type savedSession struct {
+	Files   []string // absolute paths of the decks
+	Session *flashdown.Session
+}
+
+// sessionFile returns the file where the interrupted session is saved.
+func sessionFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "flashdown", "session.json"), nil
+}
+
+// absFiles returns the absolute paths of the deck files.
+func absFiles(files []string) []string {
+	abs := make([]string, len(files))
+	for i, file := range files {
+		var err error
+		if abs[i], err = filepath.Abs(file); err != nil {
+			abs[i] = file
+		}
+	}
+	return abs
+}
+
+// loadSession returns the session interrupted with the same deck files,
+// nil if none.
+func loadSession(files []string) *flashdown.Session {
+	filename, err := sessionFile()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	var saved savedSession
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Session == nil {
+		return nil
+	}
+	if !reflect.DeepEqual(saved.Files, absFiles(files)) {
+		return nil
+	}
+	return saved.Session
+}
+
+// saveSession saves the game if it isn't finished, otherwise it removes the
+// saved session.
+func saveSession(files []string, game *flashdown.Game) error {
+	filename, err := sessionFile()
+	if err != nil {
+		return err
+	}
+	if game.IsFinished() {
+		if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(savedSession{
+		Files:   absFiles(files),
+		Session: game.Session(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+// resumeSession offers to resume the session interrupted with the same
+// decks. It returns nil if there is none, if the user declines or if the
+// decks changed too much.
+func resumeSession(files []string, decks []*flashdown.Deck) *flashdown.Game {
+	session := loadSession(files)
+	if session == nil || session.Remaining() <= 0 {
+		return nil
+	}
+	fmt.Printf("Resume the interrupted session (%d cards left)? [Y/n] ",
+		session.Remaining())
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "" && answer != "y" && answer != "yes" {
+		return nil
+	}
+	game, err := flashdown.ResumeGame(session, decks...)
+	if err != nil {
+		fmt.Printf("Cannot resume the session: %s.\n", err)
+		return nil
+	}
+	return game
+}
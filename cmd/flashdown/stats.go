@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// statsOutput is the JSON output of the stats command.
+type statsOutput struct {
+	Decks []flashdown.DeckStats
+	Total flashdown.DeckStats
+}
+
+func statsCommand(args []string) {
+	asJSON := false
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "-j", "--json", "-json":
+			asJSON = true
+		default:
+			files = append(files, deckFiles(arg)...)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	decks, err := flashdown.NewDecksFromFiles(files)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	now := flashdown.DefaultClock.Now()
+	output := statsOutput{Decks: make([]flashdown.DeckStats, len(decks))}
+	for i, deck := range decks {
+		output.Decks[i], err = flashdown.NewDeckStats(deck, now)
+		if err != nil {
+			fmt.Printf("Cannot read history of %s: %s.\n", files[i], err)
+			os.Exit(1)
+		}
+	}
+	output.Total = flashdown.TotalStats("Total", output.Decks...)
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "    ")
+		if err := encoder.Encode(output); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Deck\tCards\tToday\t7 days\t30 days\tNew\tLearning\tMature\tEasiness\tRetention\t")
+	for _, s := range append(output.Decks, output.Total) {
+		retention := "-"
+		if s.Reviews != 0 {
+			retention = fmt.Sprintf("%.0f%%", s.Retention*100)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%.2f\t%s\t\n",
+			s.Name, s.Cards, s.DueToday, s.DueWeek, s.DueMonth,
+			s.New, s.Learning, s.Mature, s.AverageEasiness, retention)
+	}
+	w.Flush()
+}
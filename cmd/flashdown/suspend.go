@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	flashdown "github.com/lugu/flashdown/internal"
+)
+
+// suspendedCommand lists the suspended and the buried cards.
+func suspendedCommand(args []string) {
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		files = append(files, deckFiles(arg)...)
+	}
+	if len(files) == 0 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	decks, err := flashdown.NewDecksFromFiles(files)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	now := flashdown.DefaultClock.Now()
+	for i, deck := range decks {
+		for _, card := range deck.HiddenCards(now) {
+			state := "suspended"
+			if !card.Meta.Suspended {
+				state = "buried until " +
+					card.Meta.Buried.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%s:%d: %s (%s)\n", files[i], card.Line,
+				card.Question, state)
+		}
+	}
+}
+
+// splitLine returns the file and the line of an argument like file:line.
+// The line is 0 if none is given.
+func splitLine(arg string) (string, int) {
+	i := strings.LastIndex(arg, ":")
+	if i < 0 {
+		return arg, 0
+	}
+	line, err := strconv.Atoi(arg[i+1:])
+	if err != nil || line <= 0 {
+		return arg, 0
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return arg, 0
+	}
+	return arg[:i], line
+}
+
+// unsuspendCommand asks again the suspended and the buried cards of the
+// decks, or only the card at a given line.
+func unsuspendCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Printf(usageMsg, os.Args[0])
+		os.Exit(1)
+	}
+	now := flashdown.DefaultClock.Now()
+	for _, arg := range args {
+		file, line := splitLine(arg)
+		for _, filename := range deckFiles(file) {
+			deck, err := flashdown.NewDeckFromFile(filename)
+			if err != nil {
+				fmt.Printf("Cannot load %s: %s.\n", filename, err)
+				os.Exit(1)
+			}
+			count := 0
+			for _, card := range deck.HiddenCards(now) {
+				if line == 0 || card.Line == line {
+					card.Meta.Unsuspend()
+					count++
+				}
+			}
+			if count == 0 {
+				continue
+			}
+			if err := deck.SaveDeckMeta(); err != nil {
+				fmt.Printf("Cannot save %s: %s.\n", filename, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s: %d cards unsuspended.\n", filename, count)
+		}
+	}
+}
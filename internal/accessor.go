@@ -13,6 +13,28 @@ type DeckAccessor interface {
 	CardsReader() (io.ReadCloser, error)
 	MetaReader() (io.ReadCloser, error)
 	MetaWriter() (io.WriteCloser, error)
+	// HistoryReader returns the review history of the deck.
+	HistoryReader() (io.ReadCloser, error)
+	// HistoryWriter returns a writer which appends to the review history.
+	HistoryWriter() (io.WriteCloser, error)
+}
+
+// MetaBackup is implemented by the accessors which keep the previous version
+// of the meta data when they are saved.
+type MetaBackup interface {
+	MetaBackupReader() (io.ReadCloser, error)
+}
+
+// CorruptedMetaKeeper is implemented by the accessors which can keep a copy
+// of corrupted meta data before it is repaired.
+type CorruptedMetaKeeper interface {
+	CorruptedMetaWriter() (io.WriteCloser, error)
+}
+
+// MediaAccessor is implemented by the accessors which can read the files
+// referenced by the cards, like images. Paths are relative to the deck.
+type MediaAccessor interface {
+	MediaReader(path string) (io.ReadCloser, error)
 }
 
 type fileAccessor struct {
@@ -30,6 +52,21 @@ func (f *fileAccessor) metaFile() string {
 	return filepath.Join(dir, base)
 }
 
+func (f *fileAccessor) backupFile() string {
+	return f.metaFile() + ".bak"
+}
+
+func (f *fileAccessor) corruptedFile() string {
+	return f.metaFile() + ".corrupt"
+}
+
+func (f *fileAccessor) historyFile() string {
+	base := filepath.Base(f.filename)
+	base = "." + base + ".log"
+	dir := filepath.Dir(f.filename)
+	return filepath.Join(dir, base)
+}
+
 func (f *fileAccessor) CardsReader() (io.ReadCloser, error) {
 	return os.Open(f.filename)
 }
@@ -39,9 +76,109 @@ func (f *fileAccessor) MetaReader() (io.ReadCloser, error) {
 }
 
 func (f *fileAccessor) MetaWriter() (io.WriteCloser, error) {
-	return os.Create(f.metaFile())
+	return newAtomicWriter(f.metaFile(), f.backupFile())
+}
+
+func (f *fileAccessor) MetaBackupReader() (io.ReadCloser, error) {
+	return os.Open(f.backupFile())
+}
+
+func (f *fileAccessor) CorruptedMetaWriter() (io.WriteCloser, error) {
+	return os.Create(f.corruptedFile())
+}
+
+func (f *fileAccessor) MediaReader(name string) (io.ReadCloser, error) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(f.filename), name)
+	}
+	return os.Open(name)
+}
+
+func (f *fileAccessor) HistoryReader() (io.ReadCloser, error) {
+	return os.Open(f.historyFile())
+}
+
+func (f *fileAccessor) HistoryWriter() (io.WriteCloser, error) {
+	return os.OpenFile(f.historyFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 }
 
 func (f *fileAccessor) DeckName() string {
 	return path.Base(f.filename)
 }
+
+// atomicWriter writes to a temporary file which replaces the destination
+// when closed, so that a crash never leaves a partially written file. The
+// previous version of the destination is kept as a backup.
+type atomicWriter struct {
+	*os.File
+	filename string
+	backup   string
+	err      error // first write error
+}
+
+func newAtomicWriter(filename, backup string) (*atomicWriter, error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	file, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicWriter{File: file, filename: filename, backup: backup}, nil
+}
+
+func (a *atomicWriter) Write(p []byte) (int, error) {
+	n, err := a.File.Write(p)
+	if err != nil && a.err == nil {
+		a.err = err
+	}
+	return n, err
+}
+
+// Close flushes the temporary file to the disk, saves the backup and
+// renames the temporary file. Nothing is replaced if a write failed.
+func (a *atomicWriter) Close() error {
+	err := a.err
+	if err == nil {
+		err = a.File.Chmod(0644)
+	}
+	if err == nil {
+		err = a.File.Sync()
+	}
+	if closeErr := a.File.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = backupFile(a.filename, a.backup)
+	}
+	if err == nil {
+		err = os.Rename(a.File.Name(), a.filename)
+	}
+	if err != nil {
+		os.Remove(a.File.Name())
+		return err
+	}
+	// Persist the rename.
+	if dir, err := os.Open(filepath.Dir(a.filename)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// backupFile copies the meta data file src to dst. Nothing is done if src
+// doesn't exist or isn't valid (see ValidMeta), so that a valid backup is
+// kept.
+func backupFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !ValidMeta(data) {
+		return nil
+	}
+	return os.WriteFile(dst, data, 0644)
+}
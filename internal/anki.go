@@ -0,0 +1,482 @@
+package flashdown
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lugu/flashdown/internal/sqlite"
+)
+
+// Anki card types.
+const (
+	ankiNew        = 0
+	ankiLearning   = 1
+	ankiReview     = 2
+	ankiRelearning = 3
+)
+
+// ankiSuspended is the queue of the suspended Anki cards.
+const ankiSuspended = -1
+
+// Anki note types.
+const (
+	ankiStandard = 0
+	ankiCloze    = 1
+)
+
+// ankiFieldSeparator separates the fields of an Anki note.
+const ankiFieldSeparator = "\x1f"
+
+var errAnkiCompressed = errors.New("Collections compressed with zstd are not supported: export with \"Support older Anki versions\"")
+
+var (
+	htmlBreak     = regexp.MustCompile(`(?i)<br\s*/?>|</?(div|p)\b[^>]*>`)
+	htmlImage     = regexp.MustCompile(`(?i)<img\b[^>]*\bsrc\s*=\s*["']([^"']*)["'][^>]*>`)
+	htmlBold      = regexp.MustCompile(`(?i)</?(b|strong)>`)
+	htmlItalic    = regexp.MustCompile(`(?i)</?(i|em)>`)
+	htmlTag       = regexp.MustCompile(`<[^>]+>`)
+	ankiSound     = regexp.MustCompile(`\[sound:([^\]]+)\]`)
+	blankLines    = regexp.MustCompile(`\n\s*\n(\s*\n)+`)
+	headingPrefix = regexp.MustCompile(`(?m)^(\s*)#`)
+)
+
+// AnkiImport describes the result of ImportAnki.
+type AnkiImport struct {
+	Files   []string // decks created
+	Notes   int      // notes imported
+	Skipped int      // notes without question or answer
+	Media   int      // media files copied
+}
+
+type ankiModel struct {
+	Type  int                     // ankiStandard or ankiCloze
+	Tmpls []struct{ Name string } // one template per card
+}
+
+type ankiDeck struct {
+	Name string
+}
+
+type ankiCard struct {
+	note, deck       int64
+	ord, kind, queue int
+	due              int64 // day, timestamp or position depending on kind
+	interval         int64 // in days for review cards
+	factor           int64 // easiness in permille
+	lapses           int64
+	modified         int64
+}
+
+type ankiNote struct {
+	id     int64
+	model  int64
+	tags   []string
+	fields []string
+	cards  []ankiCard
+}
+
+// ankiCollection is the content of an Anki collection.
+type ankiCollection struct {
+	created time.Time // day 0 of the review cards
+	models  map[int64]ankiModel
+	decks   map[int64]ankiDeck
+	notes   []*ankiNote
+}
+
+// htmlToMarkdown converts the HTML of an Anki field to Markdown.
+func htmlToMarkdown(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlImage.ReplaceAllString(s, "![]($1)")
+	s = htmlBold.ReplaceAllString(s, "**")
+	s = htmlItalic.ReplaceAllString(s, "*")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = ankiSound.ReplaceAllString(s, "[$1]($1)")
+	s = strings.ReplaceAll(s, " ", " ")
+	s = blankLines.ReplaceAllString(s, "\n\n")
+	return trim(s)
+}
+
+// escapeHeadings escapes the lines of an answer which would be read as
+// headings.
+func escapeHeadings(md string) string {
+	return headingPrefix.ReplaceAllString(md, `$1\#`)
+}
+
+// singleLine joins the lines of a question.
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// readAnkiCollection reads the tables of an Anki collection.
+func readAnkiCollection(data []byte) (*ankiCollection, error) {
+	db, err := sqlite.New(data)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Rows("col")
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("Empty Anki collection")
+	}
+	c := &ankiCollection{
+		created: time.Unix(rows[0].Int("crt"), 0),
+		models:  make(map[int64]ankiModel),
+		decks:   make(map[int64]ankiDeck),
+	}
+	var models map[string]ankiModel
+	if err := json.Unmarshal(rows[0].Bytes("models"), &models); err != nil {
+		return nil, fmt.Errorf("Invalid Anki note types: %w", err)
+	}
+	for id, model := range models {
+		i, _ := strconv.ParseInt(id, 10, 64)
+		c.models[i] = model
+	}
+	var decks map[string]ankiDeck
+	if err := json.Unmarshal(rows[0].Bytes("decks"), &decks); err != nil {
+		return nil, fmt.Errorf("Invalid Anki decks: %w", err)
+	}
+	for id, deck := range decks {
+		i, _ := strconv.ParseInt(id, 10, 64)
+		c.decks[i] = deck
+	}
+
+	rows, err = db.Rows("notes")
+	if err != nil {
+		return nil, err
+	}
+	notes := make(map[int64]*ankiNote)
+	for _, row := range rows {
+		note := &ankiNote{
+			id:     row.Int("id"),
+			model:  row.Int("mid"),
+			tags:   strings.Fields(row.String("tags")),
+			fields: strings.Split(row.String("flds"), ankiFieldSeparator),
+		}
+		notes[note.id] = note
+		c.notes = append(c.notes, note)
+	}
+	rows, err = db.Rows("cards")
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		card := ankiCard{
+			note:     row.Int("nid"),
+			deck:     row.Int("did"),
+			ord:      int(row.Int("ord")),
+			kind:     int(row.Int("type")),
+			queue:    int(row.Int("queue")),
+			due:      row.Int("due"),
+			interval: row.Int("ivl"),
+			factor:   row.Int("factor"),
+			lapses:   row.Int("lapses"),
+			modified: row.Int("mod"),
+		}
+		if odid := row.Int("odid"); odid != 0 { // filtered deck
+			card.deck = odid
+			card.due = row.Int("odue")
+		}
+		if note, ok := notes[card.note]; ok {
+			note.cards = append(note.cards, card)
+		}
+	}
+	for _, note := range notes {
+		sort.Slice(note.cards, func(i, j int) bool {
+			return note.cards[i].ord < note.cards[j].ord
+		})
+	}
+	return c, nil
+}
+
+// ankiCardID returns the identifier of the cards of an Anki note.
+func ankiCardID(note *ankiNote) string {
+	return fmt.Sprintf("anki-%d", note.id)
+}
+
+// card returns the flashdown card generated from an Anki card, used to
+// compute its digest. It returns false for the cards of the standard note
+// types after the reverse one: flashdown has no equivalent.
+func (c *ankiCollection) card(note *ankiNote, card ankiCard) (Card, bool) {
+	model := c.models[note.model]
+	result := Card{ID: ankiCardID(note)}
+	if model.Type == ankiCloze {
+		result.Cloze = card.ord + 1
+	} else if card.ord == 1 {
+		result.Reversed = true
+	} else if card.ord >= 2 {
+		return result, false
+	}
+	return result, true
+}
+
+// meta returns the meta data of a card from its Anki scheduling data.
+func (c *ankiCollection) meta(card ankiCard, now time.Time) Meta {
+	meta := Meta{NextTime: now, Easiness: defaultEasiness,
+		Lapses: int32(card.lapses), Suspended: card.queue == ankiSuspended}
+	if card.factor > 0 {
+		meta.Easiness = float32(card.factor) / 1000
+		if meta.Easiness < minimumEasiness {
+			meta.Easiness = minimumEasiness
+		}
+	}
+	switch card.kind {
+	case ankiReview:
+		meta.NextTime = c.created.AddDate(0, 0, int(card.due))
+		meta.LastTime = meta.NextTime.AddDate(0, 0, -int(card.interval))
+		// Find the SM-2 repetition which gives the Anki interval.
+		sm2 := NewSM2Scheduler()
+		meta.Repetition = 1
+		for meta.Repetition < 100 &&
+			sm2.interval(meta.Repetition, meta.Easiness) < int(card.interval) {
+			meta.Repetition++
+		}
+	case ankiLearning, ankiRelearning:
+		if card.queue == ankiLearning { // due is a timestamp
+			meta.NextTime = time.Unix(card.due, 0)
+		} else {
+			meta.NextTime = c.created.AddDate(0, 0, int(card.due))
+		}
+		meta.LastTime = time.Unix(card.modified, 0)
+		if card.modified == 0 {
+			meta.LastTime = meta.NextTime
+		}
+	}
+	return meta
+}
+
+// markdown returns the Markdown of a note, empty if the note can't be
+// converted.
+func (c *ankiCollection) markdown(note *ankiNote) string {
+	model := c.models[note.model]
+	fields := make([]string, len(note.fields))
+	for i, field := range note.fields {
+		fields[i] = htmlToMarkdown(field)
+	}
+	question := singleLine(fields[0])
+	question = hashtag.ReplaceAllString(question, `$1\#$2`)
+	answers := make([]string, 0, len(fields))
+	for _, field := range fields[1:] {
+		if field != "" {
+			answers = append(answers, escapeHeadings(field))
+		}
+	}
+	answer := strings.Join(answers, "\n\n")
+	if question == "" || (answer == "" && model.Type != ankiCloze) {
+		return ""
+	}
+	// The text of the notes must not be read as flashdown markers: only
+	// the cloze notes have cloze deletions.
+	if model.Type == ankiCloze {
+		question = escapeReverse(question)
+	} else {
+		question = escapeMarkers(question)
+	}
+	if model.Type == ankiStandard && len(model.Tmpls) >= 2 {
+		question = reverseMarker + " " + question
+		answer = escapeClozes(answer)
+	}
+	var md strings.Builder
+	fmt.Fprintf(&md, "## %s <!-- id: %s -->\n", question, ankiCardID(note))
+	// An empty tags line keeps an answer starting with "tags:" intact.
+	if len(note.tags) != 0 || tagsLine.MatchString(strings.SplitN(answer, "\n", 2)[0]) {
+		fmt.Fprintf(&md, "tags: %s\n", strings.Join(note.tags, ", "))
+	}
+	if answer != "" {
+		fmt.Fprintf(&md, "\n%s\n", answer)
+	}
+	return md.String()
+}
+
+// deckFileName returns a file name for an Anki deck.
+func deckFileName(name string) string {
+	name = strings.ReplaceAll(name, "::", " - ")
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return name + ".md"
+}
+
+// openZipFile returns the content of a file of the archive.
+func openZipFile(r *zip.ReadCloser, name string) ([]byte, error) {
+	for _, f := range r.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return nil, os.ErrNotExist
+}
+
+// ImportAnki converts an Anki package (.apkg) into Markdown decks written in
+// dir, one per Anki deck. The media files are copied into dir and the
+// progress is initialized from the Anki scheduling data.
+func ImportAnki(apkg, dir string) (*AnkiImport, error) {
+	archive, err := zip.OpenReader(apkg)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var data []byte
+	for _, name := range []string{"collection.anki21", "collection.anki2"} {
+		if data, err = openZipFile(archive, name); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		if _, zerr := openZipFile(archive, "collection.anki21b"); zerr == nil {
+			return nil, errAnkiCompressed
+		}
+		return nil, fmt.Errorf("Missing Anki collection: %w", err)
+	}
+	collection, err := readAnkiCollection(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the notes by deck.
+	result := &AnkiImport{Files: make([]string, 0)}
+	decks := make(map[int64][]*ankiNote)
+	for _, note := range collection.notes {
+		if len(note.cards) == 0 {
+			continue
+		}
+		deck := note.cards[0].deck
+		decks[deck] = append(decks[deck], note)
+	}
+	ids := make([]int64, 0, len(decks))
+	for id := range decks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return collection.decks[ids[i]].Name < collection.decks[ids[j]].Name
+	})
+
+	// The decks are converted and their files checked before anything is
+	// written, so that a name collision doesn't leave a partial import.
+	type deckFile struct {
+		filename string
+		md       string
+		metas    map[Digest]Meta
+	}
+	files := make([]deckFile, 0, len(ids))
+	filenames := make(map[string]bool)
+	now := DefaultClock.Now()
+	for _, id := range ids {
+		name := collection.decks[id].Name
+		if name == "" {
+			name = fmt.Sprintf("Anki deck %d", id)
+		}
+		header, err := yaml.Marshal(map[string]string{"title": name})
+		if err != nil {
+			return nil, err
+		}
+		var md strings.Builder
+		fmt.Fprintf(&md, "---\n%s---\n", header)
+		metas := make(map[Digest]Meta)
+		for _, note := range decks[id] {
+			card := collection.markdown(note)
+			if card == "" {
+				result.Skipped++
+				continue
+			}
+			fmt.Fprintf(&md, "\n%s", card)
+			result.Notes++
+			for _, c := range note.cards {
+				if card, ok := collection.card(note, c); ok {
+					metas[Hash(card)] = collection.meta(c, now)
+				}
+			}
+		}
+		filename := filepath.Join(dir, deckFileName(name))
+		if filenames[filename] {
+			return nil, fmt.Errorf("Several Anki decks would be written to %s", filename)
+		}
+		if _, err := os.Stat(filename); err == nil {
+			return nil, fmt.Errorf("%s already exists", filename)
+		}
+		filenames[filename] = true
+		files = append(files, deckFile{filename, md.String(), metas})
+	}
+	for _, file := range files {
+		if err := os.WriteFile(file.filename, []byte(file.md), 0644); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, file.filename)
+		if err := seedMeta(file.filename, file.metas); err != nil {
+			return nil, fmt.Errorf("Cannot save the progress of %s: %w", file.filename, err)
+		}
+	}
+
+	result.Media, err = copyAnkiMedia(archive, dir)
+	return result, err
+}
+
+// seedMeta saves the meta data of a deck file.
+func seedMeta(filename string, metas map[Digest]Meta) error {
+	deck, err := NewDeckFromFile(filename)
+	if err != nil {
+		return err
+	}
+	for _, card := range deck.Cards {
+		if meta, ok := metas[Hash(card)]; ok {
+			meta.Hash = card.Meta.Hash
+			meta.Question = card.Meta.Question
+			*card.Meta = meta
+		}
+	}
+	return deck.SaveDeckMeta()
+}
+
+// copyAnkiMedia copies the media files of an Anki package into dir. Existing
+// files are kept.
+func copyAnkiMedia(archive *zip.ReadCloser, dir string) (int, error) {
+	data, err := openZipFile(archive, "media")
+	if err != nil {
+		return 0, nil
+	}
+	var media map[string]string
+	if err := json.Unmarshal(data, &media); err != nil {
+		return 0, fmt.Errorf("Unsupported Anki media list: %w", err)
+	}
+	copied := 0
+	for entry, name := range media {
+		filename := filepath.Join(dir, filepath.Base(name))
+		if _, err := os.Stat(filename); err == nil {
+			continue
+		}
+		data, err := openZipFile(archive, entry)
+		if err != nil {
+			return copied, fmt.Errorf("Missing media %s: %w", name, err)
+		}
+		if err := os.WriteFile(filename, data, 0644); err != nil {
+			return copied, err
+		}
+		copied++
+	}
+	return copied, nil
+}
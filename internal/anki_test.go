@@ -0,0 +1,140 @@
+package flashdown
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	input := `<div>A <b>cat</b> &amp; a <i>dog</i></div><div><img src="cat.png"></div>[sound:meow.mp3]<br><br><br>end`
+	expected := "A **cat** & a *dog*\n\n![](cat.png)\n[meow.mp3](meow.mp3)\n\nend"
+	if output := htmlToMarkdown(input); output != expected {
+		t.Errorf("Invalid conversion: %q", output)
+	}
+	if output := escapeHeadings("a\n## b\n#c"); output != "a\n\\## b\n\\#c" {
+		t.Errorf("Invalid escape: %q", output)
+	}
+}
+
+func TestImportAnki(t *testing.T) {
+	DefaultClock = NewFixedClock(time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC))
+	defer func() { DefaultClock = SystemClock }()
+	dir := t.TempDir()
+	result, err := ImportAnki("samples/testdata/test.apkg", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 2 || result.Notes != 4 || result.Skipped != 0 || result.Media != 1 {
+		t.Fatalf("Invalid import: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cat.png")); err != nil {
+		t.Error(err)
+	}
+
+	deck, err := NewDeckFromFile(filepath.Join(dir, "Networking - Latency.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deck.Title() != "Networking::Latency" {
+		t.Errorf("Invalid title: %s", deck.Title())
+	}
+	// basic, both sides of a reversed card and two clozes
+	if len(deck.Cards) != 5 {
+		t.Fatalf("Invalid number of cards: %d", len(deck.Cards))
+	}
+	basic := deck.Cards[0]
+	if basic.Question != "Round trip within the same datacenter" ||
+		basic.Answer != "500 µs\nfast" || !basic.HasTag("latency") {
+		t.Errorf("Invalid card: %+v", basic)
+	}
+	if !basic.Meta.NextTime.Equal(time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)) ||
+		basic.Meta.Interval() != 20 || basic.Meta.Easiness != 2.6 {
+		t.Errorf("Invalid review meta data: %+v", basic.Meta)
+	}
+	front, back := deck.Cards[1], deck.Cards[2]
+	if front.Question != "chat" || !back.Reversed || back.Question != "cat" {
+		t.Errorf("Invalid reversed card: %+v / %+v", front, back)
+	}
+	if !front.Meta.IsNew() || back.Meta.Interval() != 5 {
+		t.Errorf("Invalid reversed meta data: %+v / %+v", front.Meta, back.Meta)
+	}
+	cloze1, cloze2 := deck.Cards[3], deck.Cards[4]
+	if cloze1.Cloze != 1 || cloze2.Cloze != 2 || !strings.HasPrefix(cloze2.Answer, "The capital of France is **Paris**") {
+		t.Errorf("Invalid cloze cards: %+v / %+v", cloze1, cloze2)
+	}
+	if !cloze1.Meta.NextTime.Equal(time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC)) || cloze1.Meta.IsNew() ||
+		!cloze2.Meta.IsNew() {
+		t.Errorf("Invalid cloze meta data: %+v / %+v", cloze1.Meta, cloze2.Meta)
+	}
+
+	deck, err = NewDeckFromFile(filepath.Join(dir, "Default.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deck.Cards) != 1 || deck.Cards[0].Question != "What does this show? ![](cat.png)" ||
+		deck.Cards[0].Answer != "**A cat** & a dog\n\\## not a heading" {
+		t.Errorf("Invalid card: %+v", deck.Cards)
+	}
+	if issues, _ := LintFile(filepath.Join(dir, "Default.md"), LintOptions{}); len(issues) != 0 {
+		t.Errorf("Unexpected issues: %v", issues)
+	}
+
+	if _, err := ImportAnki("samples/testdata/test.apkg", dir); err == nil {
+		t.Errorf("Existing decks must not be overwritten")
+	}
+
+	// Nothing is written when one of the decks exists.
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Networking - Latency.md"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportAnki("samples/testdata/test.apkg", dir); err == nil {
+		t.Errorf("Existing decks must not be overwritten")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Default.md")); !os.IsNotExist(err) {
+		t.Errorf("Partial import: %v", err)
+	}
+}
+
+func TestAnkiMarkers(t *testing.T) {
+	var basic, reversed, cloze ankiModel
+	reversed.Tmpls = make([]struct{ Name string }, 2)
+	cloze.Type = ankiCloze
+	c := &ankiCollection{models: map[int64]ankiModel{1: basic, 2: reversed, 3: cloze}}
+	notes := []*ankiNote{
+		{id: 1, model: 1, fields: []string{"Learn #golang", "tags: none"}},
+		{id: 2, model: 1, fields: []string{"&lt;-&gt; chat", "cat"}},
+		{id: 3, model: 1, fields: []string{"Syntax of {{c1::cloze}}", "text"}},
+		{id: 4, model: 2, fields: []string{"Marker", "{{c1::cloze}}"}},
+		{id: 5, model: 3, fields: []string{"&lt;-&gt; is {{c1::reverse}}", ""}},
+	}
+	var md strings.Builder
+	for _, note := range notes {
+		md.WriteString(c.markdown(note) + "\n")
+	}
+	cards, err := readCards(strings.NewReader(md.String()))
+	if err != nil {
+		t.Fatalf("%s: %s", err, md.String())
+	}
+	if len(cards) != 6 {
+		t.Fatalf("Invalid number of cards: %d", len(cards))
+	}
+	if cards[0].Question != `Learn \#golang` || cards[0].Answer != "tags: none" ||
+		len(cards[0].Tags) != 0 {
+		t.Errorf("Invalid card: %+v", cards[0])
+	}
+	if cards[1].Reversed || cards[2].Cloze != 0 || !cards[4].Reversed || cards[5].Cloze != 1 {
+		t.Errorf("Invalid cards: %+v", cards)
+	}
+
+	// Anki's "Basic (and reversed card)" has no third card.
+	note := &ankiNote{id: 4, model: 2}
+	for ord, ok := range []bool{true, true, false} {
+		if _, found := c.card(note, ankiCard{ord: ord}); found != ok {
+			t.Errorf("card %d: %v", ord, found)
+		}
+	}
+}
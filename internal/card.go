@@ -7,25 +7,57 @@ import (
 	"io/ioutil"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var (
 	errCardEmpty       = errors.New("Empty note")
 	errQuestionMissing = errors.New("Missing question")
 	errInvalidCard     = errors.New("Invalid card")
+	errReverseCloze    = errors.New("Cloze deletions in a two-sided card")
 )
 
 var splitQuestion = regexp.MustCompile(`(?m)^##\s*`)
 
+// reverseMarker starts the question of two-sided cards.
+const reverseMarker = "<->"
+
 type Card struct {
 	Question string
 	Answer   string
+	Tags     []string
+	ID       string // optional identifier, see InsertIDs
 	DeckName string
 	Meta     *Meta
+	Cloze    int    // index of the cloze deletion, 0 if none
+	Reversed bool   // true for the reverse side of a two-sided card
+	Line     int    // line of the question in the deck, cards of the same note share it
+	text     string // question before the cloze deletions are hidden
+	heading  string // question as written, before the tags are removed
+	deck     *Deck
+}
+
+// IsSibling returns true if both cards are generated from the same note
+// (ex: both sides of a two-sided card).
+func (c Card) IsSibling(other Card) bool {
+	return c.Line == other.Line && c.DeckName == other.DeckName &&
+		c.deck == other.deck
 }
 
-func (c Card) Review(s Score) {
-	c.Meta.Review(s)
+// Review updates the card meta data using the scheduler of its deck. A
+// failure of a card learned (with an interval of a day or more) is counted
+// as a lapse.
+func (c Card) Review(s Score, now time.Time) {
+	scheduler := DefaultScheduler
+	if c.deck != nil && c.deck.Scheduler != nil {
+		scheduler = c.deck.Scheduler
+	}
+	lapse := s < CorrectDifficult && c.Meta.Interval() >= 1
+	*c.Meta = scheduler.Schedule(*c.Meta, s, now)
+	c.Meta.Buried = nil
+	if lapse {
+		c.Meta.Lapses++
+	}
 }
 
 // splitCards take a mardown string as input and returns a set of cards and the line number of each.
@@ -62,7 +94,7 @@ func splitCards(md string) ([]string, []int) {
 	return cards, cardsLineNb
 }
 
-func parseCards(md string) ([]Card, error) {
+func parseCards(md string, settings DeckSettings) ([]Card, error) {
 	cards := make([]Card, 0)
 
 	sheets, lines := splitCards(md)
@@ -71,44 +103,113 @@ func parseCards(md string) ([]Card, error) {
 		if err == errCardEmpty {
 			continue
 		} else if err != nil {
-			return nil, fmt.Errorf("%w (line %d)", err, lines[i])
+			return nil, fmt.Errorf("%w (line %d)", err, lines[i]+1)
 		}
-		cards = append(cards, card)
+		card.Line = lines[i] + 1
+		cards = append(cards, expandCard(card, settings)...)
 	}
 	return cards, nil
 }
 
+// expandCard applies the deck settings to a card and returns the cards it
+// generates.
+func expandCard(card Card, settings DeckSettings) []Card {
+	cards := make([]Card, 0, 1)
+	card.Tags = mergeTags(settings.Tags, card.Tags)
+	if settings.Reverse && !strings.HasPrefix(card.Question, reverseMarker) &&
+		len(clozeIndexes(card.Question)) == 0 && card.Answer != "" {
+		card.Question = reverseMarker + card.Question
+	}
+	for _, c := range expandReverse(card) {
+		cards = append(cards, expandClozes(c)...)
+	}
+	return cards
+}
+
 func readCards(r io.Reader) ([]Card, error) {
+	cards, _, err := readDeck(r)
+	return cards, err
+}
+
+// readDeck parses the settings and the cards of a deck.
+func readDeck(r io.Reader) ([]Card, DeckSettings, error) {
 	dat, err := ioutil.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return nil, DeckSettings{}, err
 	}
-	return parseCards(string(dat))
+	settings, md, err := parseFrontMatter(string(dat))
+	if err != nil {
+		return nil, settings, err
+	}
+	cards, err := parseCards(md, settings)
+	return cards, settings, err
 }
 
 func trim(s string) string {
 	return strings.TrimSpace(strings.Trim(s, "\n"))
 }
 
+// expandReverse returns both sides of a two-sided card: the question of
+// one is the answer of the other. Other cards are returned unchanged.
+func expandReverse(c Card) []Card {
+	if !strings.HasPrefix(c.Question, reverseMarker) {
+		return []Card{c}
+	}
+	c.Question = trim(strings.TrimPrefix(c.Question, reverseMarker))
+	reverse := c
+	reverse.Question, reverse.Answer = c.Answer, c.Question
+	reverse.Reversed = true
+	reverse.heading = ""
+	return []Card{c, reverse}
+}
+
+// horizontalRule matches the lines made of a Markdown horizontal rule.
+var horizontalRule = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+
+// isRules returns true if every line of md is a horizontal rule.
+func isRules(md string) bool {
+	for _, line := range strings.Split(md, "\n") {
+		if !horizontalRule.MatchString(strings.TrimRight(line, "\r")) {
+			return false
+		}
+	}
+	return true
+}
+
 // loadCard parse a card description
 func loadCard(md string) (c Card, err error) {
+	md, c.ID = parseCardID(md)
 	md = trim(md)
-	if md == "" {
+	// A rule before the first card separates nothing.
+	if md == "" || isRules(md) {
 		return c, errCardEmpty
 	}
 	sheets := strings.SplitN(md, "\n", 2)
-	if len(sheets) != 2 {
-		return c, errInvalidCard
-	}
 	if !strings.HasPrefix(sheets[0], "##") {
 		return c, errInvalidCard
 	}
 	// Remove the '##' from the question.
-	c.Question = trim(sheets[0][2:])
+	c.heading = trim(sheets[0][2:])
+	c.Question, c.Tags = parseHeadingTags(c.heading)
 	if c.Question == "" {
 		return c, errInvalidCard
 	}
-	c.Answer = trim(sheets[1])
-	c.Meta = NewMeta(c)
+	if len(sheets) == 2 {
+		var tags []string
+		c.Answer, tags = parseTagsLine(trim(sheets[1]))
+		c.Tags = mergeTags(c.Tags, tags)
+	}
+	reverse := strings.HasPrefix(c.Question, reverseMarker)
+	if reverse && trim(strings.TrimPrefix(c.Question, reverseMarker)) == "" {
+		return c, errQuestionMissing
+	}
+	// Both sides of a two-sided card are asked: they can't be blanked.
+	if reverse && len(clozeIndexes(c.Question+c.Answer)) != 0 {
+		return c, errReverseCloze
+	}
+	// Cloze deletions don't need an answer, unless two-sided.
+	if c.Answer == "" && (reverse || len(clozeIndexes(c.Question)) == 0) {
+		return c, errInvalidCard
+	}
 	return c, nil
 }
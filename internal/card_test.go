@@ -3,6 +3,7 @@ package flashdown
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -89,3 +90,113 @@ Some code
 		t.Errorf("Wrong card: %s", cards[1])
 	}
 }
+
+func TestClozeCards(t *testing.T) {
+	input := `
+## The capital of {{c1::France}} is {{c2::Paris::city}}
+
+## {{c1::Rome}} is the capital of {{c2::Italy}} and {{c1::Rome}} is old
+Since 753 BC.
+`
+	expected := []Card{
+		{
+			Question: "The capital of [...] is Paris",
+			Answer:   "The capital of **France** is Paris",
+			Cloze:    1,
+		},
+		{
+			Question: "The capital of France is [city]",
+			Answer:   "The capital of France is **Paris**",
+			Cloze:    2,
+		},
+		{
+			Question: "[...] is the capital of Italy and [...] is old",
+			Answer:   "**Rome** is the capital of Italy and **Rome** is old\n\nSince 753 BC.",
+			Cloze:    1,
+		},
+		{
+			Question: "Rome is the capital of [...] and Rome is old",
+			Answer:   "Rome is the capital of **Italy** and Rome is old\n\nSince 753 BC.",
+			Cloze:    2,
+		},
+	}
+	cards, err := readCards(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != len(expected) {
+		t.Fatalf("Wrong length: %d", len(cards))
+	}
+	for i, card := range cards {
+		if card.Question != expected[i].Question ||
+			card.Answer != expected[i].Answer ||
+			card.Cloze != expected[i].Cloze {
+			t.Errorf("%d: %#v instead of %#v", i, card, expected[i])
+		}
+	}
+	if Hash(cards[0]) == Hash(cards[1]) {
+		t.Error("Cloze cards must have distinct digests")
+	}
+	if q := markedQuestion(cards[1]); q != "The capital of France is \uE000[city]\uE001" {
+		t.Errorf("Invalid marked question: %q", q)
+	}
+	if a := markedAnswer(cards[3]); a != "Rome is the capital of **\uE002Italy\uE003** and Rome is old\n\nSince 753 BC." {
+		t.Errorf("Invalid marked answer: %q", a)
+	}
+
+	// Editing a deletion doesn't change the digest of the other cards of
+	// the note.
+	edited, err := readCards(bytes.NewBufferString(strings.Replace(input,
+		"{{c2::Paris::city}}", "{{c2::Lyon}}", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Hash(edited[0]) != Hash(cards[0]) {
+		t.Error("Editing c2 changed the digest of c1")
+	}
+}
+
+func TestReverseCards(t *testing.T) {
+	input := `
+## <-> chat
+cat
+
+## chien
+dog
+`
+	cards, err := readCards(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 3 {
+		t.Fatalf("Wrong length: %d", len(cards))
+	}
+	if cards[0].Question != "chat" || cards[0].Answer != "cat" ||
+		cards[0].Reversed {
+		t.Errorf("Invalid forward card: %#v", cards[0])
+	}
+	if cards[1].Question != "cat" || cards[1].Answer != "chat" ||
+		!cards[1].Reversed {
+		t.Errorf("Invalid reverse card: %#v", cards[1])
+	}
+	if !cards[0].IsSibling(cards[1]) || cards[0].IsSibling(cards[2]) {
+		t.Error("Invalid siblings")
+	}
+	if Hash(cards[0]) == Hash(cards[1]) {
+		t.Error("Both sides must have distinct digests")
+	}
+	cards = SeparateSiblings(cards)
+	if cards[0].IsSibling(cards[1]) || cards[1].IsSibling(cards[2]) {
+		t.Error("Siblings should be separated")
+	}
+	for _, input := range []string{
+		"## <->\nanswer",
+		"## <-> question",
+		"## <-> The capital of {{c1::France}}",
+		"## <-> capital\n{{c1::Paris}}",
+	} {
+		if _, err := readCards(bytes.NewBufferString(input)); err == nil {
+			t.Errorf("missing error: %q", input)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package flashdown
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time. It lets the caller control the time used to select
+// and review the cards.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock returns the current local time.
+var SystemClock Clock = systemClock{}
+
+// DefaultClock is the clock used by new decks and games.
+var DefaultClock = SystemClock
+
+// FixedClock is a Clock which only changes when told to. It is useful to
+// simulate a sequence of reviews.
+type FixedClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewFixedClock returns a clock set to now.
+func NewFixedClock(now time.Time) *FixedClock {
+	return &FixedClock{now: now}
+}
+
+func (c *FixedClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Set changes the time of the clock.
+func (c *FixedClock) Set(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = now
+}
+
+// Add moves the clock forward by d.
+func (c *FixedClock) Add(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,131 @@
+package flashdown
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clozePattern matches cloze deletions like {{c1::Paris}} or
+// {{c1::Paris::capital}} where "capital" is a hint.
+var clozePattern = regexp.MustCompile(`\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+
+// clozeIndexes returns the cloze indexes used in s, sorted.
+func clozeIndexes(s string) []int {
+	seen := make(map[int]bool)
+	indexes := make([]int, 0)
+	for _, match := range clozePattern.FindAllStringSubmatch(s, -1) {
+		index, err := strconv.Atoi(match[1])
+		if err != nil || seen[index] {
+			continue
+		}
+		seen[index] = true
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// replaceClozes replaces the cloze deletions of s. The deletions with the
+// given index are replaced by blank(text, hint), the others by their text.
+func replaceClozes(s string, index int, blank func(text, hint string) string) string {
+	return clozePattern.ReplaceAllStringFunc(s, func(cloze string) string {
+		match := clozePattern.FindStringSubmatch(cloze)
+		if i, _ := strconv.Atoi(match[1]); i != index {
+			return match[2]
+		}
+		return blank(match[2], match[3])
+	})
+}
+
+// The marks delimiting the blanked and the revealed deletions in the texts
+// returned by Game.MarkedQuestion and Game.MarkedAnswer. The front-ends
+// replace them with a style of their own. They are private use characters
+// which never appear in a deck.
+const (
+	ClozeBlankStart  = '\uE000'
+	ClozeBlankEnd    = '\uE001'
+	ClozeRevealStart = '\uE002'
+	ClozeRevealEnd   = '\uE003'
+)
+
+// blank returns the text replacing a hidden deletion.
+func blank(hint string) string {
+	if hint != "" {
+		return "[" + hint + "]"
+	}
+	return "[...]"
+}
+
+// hideCloze returns s with the deletion index blanked.
+func hideCloze(s string, index int) string {
+	return replaceClozes(s, index, func(text, hint string) string {
+		return blank(hint)
+	})
+}
+
+// revealCloze returns s with the deletion index highlighted.
+func revealCloze(s string, index int) string {
+	return replaceClozes(s, index, func(text, hint string) string {
+		return "**" + text + "**"
+	})
+}
+
+// markedQuestion returns the question of a card with its blanked deletion
+// delimited by ClozeBlankStart and ClozeBlankEnd.
+func markedQuestion(c Card) string {
+	if c.Cloze == 0 || c.text == "" {
+		return c.Question
+	}
+	return replaceClozes(c.text, c.Cloze, func(text, hint string) string {
+		return string(ClozeBlankStart) + blank(hint) + string(ClozeBlankEnd)
+	})
+}
+
+// markedAnswer returns the answer of a card with its revealed deletion
+// delimited by ClozeRevealStart and ClozeRevealEnd.
+func markedAnswer(c Card) string {
+	if c.Cloze == 0 || c.text == "" {
+		return c.Answer
+	}
+	answer := strings.TrimPrefix(c.Answer, revealCloze(c.text, c.Cloze))
+	return replaceClozes(c.text, c.Cloze, func(text, hint string) string {
+		return "**" + string(ClozeRevealStart) + text + string(ClozeRevealEnd) + "**"
+	}) + answer
+}
+
+// clozeDigestText returns the text identifying the cloze card index of s:
+// the other deletions are left out so that editing them doesn't change the
+// digest of the card.
+func clozeDigestText(s string, index int) string {
+	return clozePattern.ReplaceAllStringFunc(s, func(cloze string) string {
+		match := clozePattern.FindStringSubmatch(cloze)
+		if i, _ := strconv.Atoi(match[1]); i != index {
+			return ""
+		}
+		return "c" + match[1]
+	})
+}
+
+// expandClozes returns one card per cloze index found in the question. The
+// question of each card has the deletion blanked and the answer starts with
+// the deletion revealed. Cards without cloze are returned unchanged.
+func expandClozes(c Card) []Card {
+	indexes := clozeIndexes(c.Question)
+	if len(indexes) == 0 {
+		return []Card{c}
+	}
+	cards := make([]Card, len(indexes))
+	for i, index := range indexes {
+		cards[i] = c
+		cards[i].Cloze = index
+		cards[i].text = c.Question
+		cards[i].Question = hideCloze(c.Question, index)
+		cards[i].Answer = revealCloze(c.Question, index)
+		if c.Answer != "" {
+			cards[i].Answer += "\n\n" + c.Answer
+		}
+	}
+	return cards
+}
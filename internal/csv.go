@@ -0,0 +1,267 @@
+package flashdown
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Roles of the columns of a CSV file.
+const (
+	ColumnQuestion = "question"
+	ColumnAnswer   = "answer"
+	ColumnTags     = "tags"
+	ColumnIgnored  = ""
+)
+
+// Header detection of ImportCSV.
+const (
+	HeaderDetect  = iota // the first row is a header if it names the columns
+	HeaderPresent        // the first row is a header
+	HeaderAbsent         // the first row is a card
+)
+
+// columnNames associates the usual header names with the column roles.
+var columnNames = map[string]string{
+	"question":   ColumnQuestion,
+	"front":      ColumnQuestion,
+	"term":       ColumnQuestion,
+	"answer":     ColumnAnswer,
+	"back":       ColumnAnswer,
+	"definition": ColumnAnswer,
+	"tags":       ColumnTags,
+	"tag":        ColumnTags,
+}
+
+// hashtag matches the words of a question which would be read as tags.
+var hashtag = regexp.MustCompile(`(\s)#(\pL)`)
+
+// clozeStart matches the beginning of a cloze deletion.
+var clozeStart = regexp.MustCompile(`\{\{(c\d+::)`)
+
+var errNoQuestionColumn = errors.New("No question column")
+
+// CSVOptions describes the layout of a CSV file.
+type CSVOptions struct {
+	// Comma is the field delimiter. If 0, a tab, a semicolon or a comma is
+	// detected from the first line.
+	Comma rune
+	// Columns gives the role of each column: ColumnQuestion, ColumnAnswer,
+	// ColumnTags or ColumnIgnored. If nil, the roles are read from the
+	// header or the columns are question, answer and tags.
+	Columns []string
+	// Header is HeaderDetect, HeaderPresent or HeaderAbsent.
+	Header int
+	// Title is the title of the deck, none if empty.
+	Title string
+	// Markdown keeps the "<->" and the cloze deletions of the questions,
+	// like the files written by ExportCSV. Otherwise they are escaped.
+	Markdown bool
+}
+
+// CSVImport describes the result of ImportCSV.
+type CSVImport struct {
+	File    string // deck created by ImportCSVFile
+	Notes   int    // notes imported
+	Skipped int    // rows without question or answer
+}
+
+// detectComma returns the delimiter used in the first line of a file.
+func detectComma(data string) rune {
+	line := strings.SplitN(data, "\n", 2)[0]
+	switch {
+	case strings.Contains(line, "\t"):
+		return '\t'
+	case strings.Count(line, ";") > strings.Count(line, ","):
+		return ';'
+	}
+	return ','
+}
+
+// headerColumns returns the roles of the columns named by a header, or nil
+// if the row isn't a header.
+func headerColumns(row []string) []string {
+	columns := make([]string, len(row))
+	found := false
+	for i, name := range row {
+		role, ok := columnNames[strings.ToLower(strings.TrimSpace(name))]
+		if ok && role == ColumnQuestion {
+			found = true
+		}
+		columns[i] = role
+	}
+	if !found {
+		return nil
+	}
+	return columns
+}
+
+// escapeReverse escapes the "<->" at the beginning of a question.
+func escapeReverse(q string) string {
+	if strings.HasPrefix(q, reverseMarker) {
+		return `\` + q
+	}
+	return q
+}
+
+// escapeClozes escapes the cloze deletions of a question or an answer.
+func escapeClozes(s string) string {
+	return clozeStart.ReplaceAllString(s, `{\{$1`)
+}
+
+// escapeMarkers escapes the "<->" and the cloze deletions of a question so
+// that it stays a one-sided card.
+func escapeMarkers(q string) string {
+	return escapeClozes(escapeReverse(q))
+}
+
+// csvCard returns the Markdown of a card, empty if the row has no question
+// or no answer.
+func csvCard(row, columns []string, markdown bool) string {
+	var question, answer []string
+	var tags []string
+	for i, field := range row {
+		if i >= len(columns) || strings.TrimSpace(field) == "" {
+			continue
+		}
+		switch columns[i] {
+		case ColumnQuestion:
+			question = append(question, field)
+		case ColumnAnswer:
+			answer = append(answer, trim(field))
+		case ColumnTags:
+			tags = append(tags, strings.FieldsFunc(field, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})...)
+		}
+	}
+	q := singleLine(strings.Join(question, " "))
+	q = hashtag.ReplaceAllString(q, `$1\#$2`)
+	if !markdown {
+		q = escapeMarkers(q)
+	}
+	a := escapeHeadings(strings.Join(answer, "\n\n"))
+	if q == "" || (a == "" && len(clozeIndexes(q)) == 0) {
+		return ""
+	}
+	var md strings.Builder
+	fmt.Fprintf(&md, "## %s\n", q)
+	// An empty tags line keeps an answer starting with "tags:" intact.
+	if len(tags) != 0 || tagsLine.MatchString(strings.SplitN(a, "\n", 2)[0]) {
+		fmt.Fprintf(&md, "tags: %s\n", strings.Join(tags, ", "))
+	}
+	if a != "" {
+		fmt.Fprintf(&md, "\n%s\n", a)
+	}
+	return md.String()
+}
+
+// ImportCSV converts a CSV or TSV file (ex: a Quizlet export) into a
+// Markdown deck. Fields can be quoted and span multiple lines.
+func ImportCSV(r io.Reader, w io.Writer, options CSVOptions) (*CSVImport, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = options.Comma
+	if reader.Comma == 0 {
+		reader.Comma = detectComma(text)
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := options.Columns
+	if len(rows) != 0 && options.Header != HeaderAbsent {
+		header := headerColumns(rows[0])
+		if header != nil || options.Header == HeaderPresent {
+			rows = rows[1:]
+		}
+		if columns == nil {
+			columns = header
+		}
+	}
+	if columns == nil {
+		columns = []string{ColumnQuestion, ColumnAnswer, ColumnTags}
+	}
+	hasQuestion := false
+	for _, column := range columns {
+		switch column {
+		case ColumnQuestion:
+			hasQuestion = true
+		case ColumnAnswer, ColumnTags, ColumnIgnored:
+		default:
+			return nil, fmt.Errorf("Unknown column: %s", column)
+		}
+	}
+	if !hasQuestion {
+		return nil, errNoQuestionColumn
+	}
+
+	var md strings.Builder
+	if options.Title != "" {
+		header, err := yaml.Marshal(map[string]string{"title": options.Title})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&md, "---\n%s---\n", header)
+	}
+	result := &CSVImport{}
+	for _, row := range rows {
+		card := csvCard(row, columns, options.Markdown)
+		if card == "" {
+			result.Skipped++
+			continue
+		}
+		if md.Len() != 0 {
+			md.WriteString("\n")
+		}
+		md.WriteString(card)
+		result.Notes++
+	}
+	if _, err := io.WriteString(w, md.String()); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ImportCSVFile converts a CSV or TSV file into a Markdown deck written in
+// dir with the same base name.
+func ImportCSVFile(filename, dir string, options CSVOptions) (*CSVImport, error) {
+	r, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	output := filepath.Join(dir, base+".md")
+	w, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%s already exists", output)
+	} else if err != nil {
+		return nil, err
+	}
+	result, err := ImportCSV(r, w, options)
+	if err != nil {
+		w.Close()
+		os.Remove(output)
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	result.File = output
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package flashdown
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func importCSV(t *testing.T, input string, options CSVOptions) ([]Card, *CSVImport) {
+	var md bytes.Buffer
+	result, err := ImportCSV(strings.NewReader(input), &md, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cards, err := readCards(&md)
+	if err != nil {
+		t.Fatalf("%s: %s", err, md.String())
+	}
+	return cards, result
+}
+
+func TestImportCSV(t *testing.T) {
+	input := "Tags,Front,Back\n" +
+		"geo europe,France,\"Paris\n## not a heading\n# neither\"\n" +
+		",Learn #golang,\"tags: none\"\n" +
+		",,missing question\n" +
+		",No answer,\n" +
+		"\"a, b\",The capital of {{c1::Italy}},Rome\n" +
+		",<-> chat,cat\n"
+	cards, result := importCSV(t, input, CSVOptions{})
+	if result.Notes != 4 || result.Skipped != 2 {
+		t.Errorf("Invalid result: %+v", result)
+	}
+	if len(cards) != 4 {
+		t.Fatalf("Invalid number of cards: %d", len(cards))
+	}
+	if cards[0].Question != "France" || cards[0].Answer != "Paris\n\\## not a heading\n\\# neither" ||
+		!reflect.DeepEqual(cards[0].Tags, []string{"geo", "europe"}) {
+		t.Errorf("Invalid card: %+v", cards[0])
+	}
+	if cards[1].Question != `Learn \#golang` || cards[1].Answer != "tags: none" ||
+		len(cards[1].Tags) != 0 {
+		t.Errorf("Invalid card: %+v", cards[1])
+	}
+	if cards[2].Question != `The capital of {\{c1::Italy}}` || cards[2].Cloze != 0 ||
+		!reflect.DeepEqual(cards[2].Tags, []string{"a", "b"}) {
+		t.Errorf("Invalid card: %+v", cards[2])
+	}
+	if cards[3].Question != `\<-> chat` || cards[3].Reversed {
+		t.Errorf("Invalid card: %+v", cards[3])
+	}
+	cards, _ = importCSV(t, input, CSVOptions{Markdown: true})
+	if len(cards) != 5 || cards[2].Cloze != 1 || !cards[4].Reversed {
+		t.Errorf("Invalid cards: %+v", cards)
+	}
+}
+
+func TestImportCSVOptions(t *testing.T) {
+	// Quizlet exports a definition and a term separated by a tab.
+	cards, _ := importCSV(t, "Paris\tFrance\nRome\tItaly\n", CSVOptions{
+		Columns: []string{ColumnAnswer, ColumnQuestion},
+	})
+	if len(cards) != 2 || cards[1].Question != "Italy" || cards[1].Answer != "Rome" {
+		t.Errorf("Invalid cards: %+v", cards)
+	}
+	// Semicolons are detected, the first row is a card.
+	cards, _ = importCSV(t, "question 1;answer 1;x\nquestion 2;answer 2;y\n",
+		CSVOptions{Title: "Test"})
+	if len(cards) != 2 || cards[0].Answer != "answer 1" || !cards[0].HasTag("x") {
+		t.Errorf("Invalid cards: %+v", cards)
+	}
+	cards, _ = importCSV(t, "q|a\nquestion|answer\n", CSVOptions{
+		Comma:  '|',
+		Header: HeaderPresent,
+	})
+	if len(cards) != 1 || cards[0].Question != "question" {
+		t.Errorf("Invalid cards: %+v", cards)
+	}
+	cards, _ = importCSV(t, "question,answer\n", CSVOptions{Header: HeaderAbsent})
+	if len(cards) != 1 || cards[0].Question != "question" {
+		t.Errorf("Invalid cards: %+v", cards)
+	}
+	for _, columns := range [][]string{{ColumnAnswer}, {"unknown"}} {
+		_, err := ImportCSV(strings.NewReader("a,b\n"), &bytes.Buffer{},
+			CSVOptions{Columns: columns})
+		if err == nil {
+			t.Errorf("%v: error expected", columns)
+		}
+	}
+}
+
+func TestImportExportedCSV(t *testing.T) {
+	deck, dir := newExportDeck(t)
+	filename := filepath.Join(dir, "exported.csv")
+	var buf bytes.Buffer
+	if err := ExportCSV(&buf, ',', deck); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ImportCSVFile(filename, dir, CSVOptions{Markdown: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.File != filepath.Join(dir, "exported.md") || result.Notes != 3 {
+		t.Errorf("Invalid result: %+v", result)
+	}
+	imported, err := NewDeckFromFile(result.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.Cards) != len(deck.Cards) {
+		t.Fatalf("Invalid number of cards: %d", len(imported.Cards))
+	}
+	for i, card := range imported.Cards {
+		original := deck.Cards[i]
+		if card.Question != original.Question || card.Answer != original.Answer ||
+			!reflect.DeepEqual(card.Tags, original.Tags) {
+			t.Errorf("%d: invalid card: %+v", i, card)
+		}
+	}
+	if _, err := ImportCSVFile(filename, dir, CSVOptions{Markdown: true}); err == nil {
+		t.Errorf("Existing deck overwritten")
+	}
+}
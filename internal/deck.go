@@ -1,83 +1,173 @@
 package flashdown
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"log"
 	"math/rand"
 	"time"
 )
 
+// ErrCorruptedMeta is returned when the meta data of a deck can't be read.
+// The progress isn't lost: see RecoverMeta.
+var ErrCorruptedMeta = errors.New("Corrupted progress file")
+
 // MetaMap associate the index if the cards with its meta data.
 type MetaMap map[Digest]*Meta
 
 type Deck struct {
-	Cards      []Card
-	Name       string
-	MetaWriter func() (io.WriteCloser, error)
+	Cards         []Card
+	Name          string
+	Settings      DeckSettings
+	MetaWriter    func() (io.WriteCloser, error)
+	HistoryReader func() (io.ReadCloser, error)
+	HistoryWriter func() (io.WriteCloser, error)
+	MediaReader   func(path string) (io.ReadCloser, error) // nil if unsupported
+	Scheduler     Scheduler                                // used to review the cards
+	Clock         Clock                                    // used to tell which cards are due
+	Checksum      string                                   // checksum of the Markdown file
+	created       time.Time                                // creation of the meta data
 }
 
-func loadCards(accessor DeckAccessor) ([]Card, error) {
+func loadCards(accessor DeckAccessor) ([]Card, DeckSettings, string, error) {
 	cardReader, err := accessor.CardsReader()
 	if err != nil {
-		return nil, err
+		return nil, DeckSettings{}, "", err
 	}
 	defer cardReader.Close()
 
-	cards, err := readCards(cardReader)
+	data, err := ioutil.ReadAll(cardReader)
 	if err != nil {
-		return nil, err
+		return nil, DeckSettings{}, "", err
 	}
-	return cards, nil
+	cards, settings, err := readDeck(bytes.NewReader(data))
+	return cards, settings, checksum(data), err
+}
+
+// checksum returns the checksum of a deck file.
+func checksum(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
 }
 
-func newMetaCards(accessor DeckAccessor) error {
-	metas := make([]Meta, 0)
+func newMetaCards(accessor DeckAccessor, now time.Time) error {
+	db := metaDB{Created: now}
 	metaWriter, err := accessor.MetaWriter()
 	if err != nil {
 		return fmt.Errorf("Cannot create DB: %v", err)
 	}
-	defer metaWriter.Close()
-
-	if err = writeDB(metaWriter, metas); err != nil {
+	if err = writeDB(metaWriter, db); err != nil {
+		metaWriter.Close()
 		return fmt.Errorf("Cannot write DB: %v", err)
 	}
-	return nil
+	return metaWriter.Close()
 }
 
-func loadMetaMap(accessor DeckAccessor) (MetaMap, error) {
+// loadMetaMap returns the meta data of the deck indexed by digest and the
+// creation date of the meta data.
+func loadMetaMap(accessor DeckAccessor, clock Clock) (MetaMap, time.Time, error) {
 	var metaMap MetaMap = make(map[Digest]*Meta)
 
 	metaReader, err := accessor.MetaReader()
 	if err != nil {
-		err = newMetaCards(accessor)
+		now := clock.Now()
+		err = newMetaCards(accessor, now)
 		if err != nil {
-			return nil, err
+			return nil, time.Time{}, err
 		}
-		return metaMap, nil
+		return metaMap, now, nil
 	}
 	defer metaReader.Close()
 
-	metas, err := readDB(metaReader)
-	if err != nil {
-		err = newMetaCards(accessor)
-		if err != nil {
-			return nil, err
-		}
-		return metaMap, nil
+	db, err := readDB(metaReader)
+	if errors.Is(err, ErrMetaVersion) {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", accessor.DeckName(), err)
+	} else if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w (%v)", accessor.DeckName(),
+			ErrCorruptedMeta, err)
 	}
 
+	metas := db.Cards
 	for i := range metas {
 		metaMap[metas[i].Hash] = &metas[i]
 	}
-	return metaMap, nil
+	if db.Created.IsZero() {
+		db.Created = clock.Now()
+	}
+	return metaMap, db.Created, nil
+}
+
+// keepCorruptedMeta copies the meta data of a deck aside if the accessor
+// supports it.
+func keepCorruptedMeta(accessor DeckAccessor) error {
+	keeper, ok := accessor.(CorruptedMetaKeeper)
+	if !ok {
+		return nil
+	}
+	r, err := accessor.MetaReader()
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	w, err := keeper.CorruptedMetaWriter()
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// RecoverMeta repairs the corrupted meta data of a deck. The backup is
+// restored if the accessor keeps a valid one, otherwise the progress is
+// reset. A copy of the corrupted meta data is kept if the accessor
+// implements CorruptedMetaKeeper. It returns true if the backup was
+// restored.
+func RecoverMeta(accessor DeckAccessor) (bool, error) {
+	if err := keepCorruptedMeta(accessor); err != nil {
+		return false, fmt.Errorf("Cannot keep the corrupted progress: %w", err)
+	}
+	db := metaDB{Created: DefaultClock.Now()}
+	restored := false
+	if backup, ok := accessor.(MetaBackup); ok {
+		if r, err := backup.MetaBackupReader(); err == nil {
+			if b, err := readDB(r); err == nil {
+				db, restored = b, true
+			}
+			r.Close()
+		}
+	}
+	w, err := accessor.MetaWriter()
+	if err != nil {
+		return false, err
+	}
+	if err := writeDB(w, db); err != nil {
+		w.Close()
+		return false, err
+	}
+	return restored, w.Close()
+}
+
+// RecoverMetaFromFile repairs the corrupted meta data of a deck file.
+func RecoverMetaFromFile(filename string) (bool, error) {
+	return RecoverMeta(newFileDeckAccessor(filename))
 }
 
 // NewDeckFromFile reads a Deck from a file.
 func NewEmptyDeck(name string) *Deck {
 	return &Deck{
-		Cards:      []Card{},
-		Name:       name,
-		MetaWriter: func() (io.WriteCloser, error) { return nil, fmt.Errorf("%s", name) },
+		Cards:         []Card{},
+		Name:          name,
+		MetaWriter:    func() (io.WriteCloser, error) { return nil, fmt.Errorf("%s", name) },
+		HistoryReader: func() (io.ReadCloser, error) { return nil, fmt.Errorf("%s", name) },
+		HistoryWriter: func() (io.WriteCloser, error) { return nil, fmt.Errorf("%s", name) },
+		Scheduler:     DefaultScheduler,
+		Clock:         DefaultClock,
 	}
 }
 
@@ -88,32 +178,73 @@ func NewDeckFromFile(filename string) (*Deck, error) {
 
 // NewDeck reads a Deck from DeckAccessor
 func NewDeck(accessor DeckAccessor) (*Deck, error) {
-	cards, err := loadCards(accessor)
+	return NewDeckWithClock(accessor, DefaultClock)
+}
+
+// NewDeckWithClock reads a Deck from DeckAccessor. The clock tells which
+// cards are due and dates the new cards.
+func NewDeckWithClock(accessor DeckAccessor, clock Clock) (*Deck, error) {
+	cards, settings, sum, err := loadCards(accessor)
+	if err != nil {
+		return nil, err
+	}
+	scheduler, err := settings.Scheduler.newScheduler(DefaultScheduler)
 	if err != nil {
 		return nil, err
 	}
-	metaMap, err := loadMetaMap(accessor)
+	metaMap, created, err := loadMetaMap(accessor, clock)
 	if err != nil {
 		return nil, err
 	}
 
-	deckName := accessor.DeckName()
+	deck := &Deck{
+		Cards:         cards,
+		Name:          accessor.DeckName(),
+		Settings:      settings,
+		MetaWriter:    accessor.MetaWriter,
+		HistoryReader: accessor.HistoryReader,
+		HistoryWriter: accessor.HistoryWriter,
+		Scheduler:     scheduler,
+		Clock:         clock,
+		Checksum:      sum,
+		created:       created,
+	}
+	if media, ok := accessor.(MediaAccessor); ok {
+		deck.MediaReader = media.MediaReader
+	}
+	orphans := make(MetaMap, len(metaMap))
+	for hash, meta := range metaMap {
+		orphans[hash] = meta
+	}
 	for i := range cards {
-		cards[i].DeckName = deckName
-		hash := Hash(cards[i])
-		meta, ok := metaMap[hash]
-		if ok {
+		cards[i].DeckName = deck.Name
+		cards[i].deck = deck
+		if meta, ok := matchMeta(metaMap, cards[i]); ok {
 			cards[i].Meta = meta
-		} else {
-			cards[i].Meta = NewMeta(cards[i])
+			delete(orphans, meta.Hash)
 		}
 	}
+	rematchMetas(cards, orphans)
+	for i := range cards {
+		if cards[i].Meta == nil {
+			cards[i].Meta = NewMeta(cards[i], deck.Clock.Now())
+		}
+		cards[i].Meta.Hash = Hash(cards[i])
+		cards[i].Meta.Question = cards[i].Question
+	}
+	for _, duplicates := range duplicateCards(cards) {
+		log.Printf("Warning: %s: cards at lines %d and %d have the same digest, add an id to one of them",
+			deck.Name, duplicates[0].Line, duplicates[1].Line)
+	}
+	return deck, nil
+}
 
-	return &Deck{
-		Cards:      cards,
-		Name:       accessor.DeckName(),
-		MetaWriter: accessor.MetaWriter,
-	}, nil
+// Title returns the title of the deck from its settings, or its name.
+func (d *Deck) Title() string {
+	if d.Settings.Title != "" {
+		return d.Settings.Title
+	}
+	return d.Name
 }
 
 func ShuffleCards(cards []Card) []Card {
@@ -121,11 +252,41 @@ func ShuffleCards(cards []Card) []Card {
 	return cards
 }
 
-// SelectBefore returns the cards to be review before a given date.
+// SeparateSiblings reorders the cards so that cards generated from the same
+// note are not next to each other when possible.
+func SeparateSiblings(cards []Card) []Card {
+	for i := 1; i < len(cards); i++ {
+		if !cards[i].IsSibling(cards[i-1]) {
+			continue
+		}
+		for j := i + 1; j < len(cards); j++ {
+			if !cards[j].IsSibling(cards[i-1]) {
+				cards[i], cards[j] = cards[j], cards[i]
+				break
+			}
+		}
+	}
+	return cards
+}
+
+// SelectBefore returns the cards to be review before a given date. The
+// suspended cards and the cards buried at that date are never selected.
 func (d *Deck) SelectBefore(now time.Time) []Card {
 	cards := []Card{}
 	for _, card := range d.Cards {
-		if card.Meta.NextTime.Before(now) {
+		if card.Meta.NextTime.Before(now) && !card.Meta.Suspended &&
+			!card.Meta.IsBuried(now) {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// HiddenCards returns the cards suspended or buried at the time now.
+func (d *Deck) HiddenCards(now time.Time) []Card {
+	cards := []Card{}
+	for _, card := range d.Cards {
+		if card.Meta.Suspended || card.Meta.IsBuried(now) {
 			cards = append(cards, card)
 		}
 	}
@@ -134,9 +295,10 @@ func (d *Deck) SelectBefore(now time.Time) []Card {
 
 func (d *Deck) Stats() (toReview, total int) {
 	toReview = 0
-	now := time.Now()
+	now := d.Clock.Now()
 	for _, card := range d.Cards {
-		if card.Meta.NextTime.Before(now) {
+		if card.Meta.NextTime.Before(now) && !card.Meta.Suspended &&
+			!card.Meta.IsBuried(now) {
 			toReview++
 		}
 	}
@@ -144,14 +306,65 @@ func (d *Deck) Stats() (toReview, total int) {
 }
 
 func (d *Deck) SaveDeckMeta() error {
-	metas := make([]Meta, len(d.Cards))
+	db := metaDB{
+		Checksum: d.Checksum,
+		Created:  d.created,
+		Updated:  d.Clock.Now(),
+		Cards:    make([]Meta, len(d.Cards)),
+	}
+	if d.Scheduler != nil {
+		db.Scheduler = d.Scheduler.Name()
+	}
 	for i := range d.Cards {
-		metas[i] = *d.Cards[i].Meta
+		db.Cards[i] = *d.Cards[i].Meta
 	}
 	metaWriter, err := d.MetaWriter()
 	if err != nil {
 		return err
 	}
-	defer metaWriter.Close()
-	return writeDB(metaWriter, metas)
+	if err := writeDB(metaWriter, db); err != nil {
+		metaWriter.Close()
+		return err
+	}
+	return metaWriter.Close()
+}
+
+// History returns the reviews of the deck, oldest first. A deck without
+// history returns an empty list.
+func (d *Deck) History() ([]ReviewLog, error) {
+	historyReader, err := d.HistoryReader()
+	if err != nil {
+		return []ReviewLog{}, nil
+	}
+	defer historyReader.Close()
+	logs, skipped, err := readHistory(historyReader)
+	if len(skipped) != 0 {
+		log.Printf("Warning: %s: %d malformed reviews skipped in the history (line %d)",
+			d.Name, len(skipped), skipped[0])
+	}
+	return logs, err
+}
+
+// StudiedToday returns the number of new cards and of cards in review
+// studied on the day of now, according to the history of the deck.
+func (d *Deck) StudiedToday(now time.Time) (newCards, reviews int) {
+	logs, err := d.History()
+	if err != nil {
+		log.Printf("Cannot read the history of %s: %s", d.Name, err)
+		return 0, 0
+	}
+	return studiedToday(logs, now)
+}
+
+// AppendHistory adds reviews at the end of the deck history.
+func (d *Deck) AppendHistory(logs ...ReviewLog) error {
+	historyWriter, err := d.HistoryWriter()
+	if err != nil {
+		return err
+	}
+	if err := writeHistory(historyWriter, logs...); err != nil {
+		historyWriter.Close()
+		return err
+	}
+	return historyWriter.Close()
 }
@@ -1,9 +1,11 @@
 package flashdown
 
 import (
+	"errors"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -27,11 +29,10 @@ func TestMissingAnswer(t *testing.T) {
 }
 
 func TestCreateDB(t *testing.T) {
-	file, err := ioutil.TempFile("samples/testdata", "deck")
+	file, err := ioutil.TempFile(t.TempDir(), "deck")
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer os.Remove(file.Name())
 	_, err = file.Write([]byte(`
 ## question 1
 answer 1
@@ -50,5 +51,80 @@ answer 2
 	if err != nil {
 		t.Error(err)
 	}
-	os.Remove(file.Name() + ".db")
+}
+
+func TestCorruptedDB(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "deck.md")
+	accessor := newFileDeckAccessor(filename).(*fileAccessor)
+	err := os.WriteFile(filename, []byte("## question 1\nanswer 1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDeckFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Cards[0].Review(PerfectRecall, time.Now())
+	if err := d.SaveDeckMeta(); err != nil {
+		t.Fatal(err)
+	}
+	// The second save keeps the first one as a backup.
+	if err := d.SaveDeckMeta(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(accessor.metaFile(), []byte("[{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewDeckFromFile(filename)
+	if !errors.Is(err, ErrCorruptedMeta) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	restored, err := RecoverMeta(accessor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored {
+		t.Errorf("Backup not restored")
+	}
+	d, err = NewDeckFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Cards[0].Meta.Repetition != 1 {
+		t.Errorf("Progress lost: %d", d.Cards[0].Meta.Repetition)
+	}
+	entries, err := os.ReadDir(filepath.Dir(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("Unexpected files: %v", entries)
+	}
+	if data, err := os.ReadFile(accessor.corruptedFile()); err != nil ||
+		string(data) != "[{" {
+		t.Errorf("Corrupted file not kept: %q, %v", data, err)
+	}
+}
+
+func TestAtomicWriter(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, ".deck.md.db")
+	if err := os.WriteFile(filename, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := newAtomicWriter(filename, filename+".bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.err = errors.New("disk full") // simulate a failed write
+	if err := w.Close(); err == nil {
+		t.Error("Missing error")
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil || string(data) != "[]" {
+		t.Errorf("File modified: %q, %v", data, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Temporary file not removed: %v", entries)
+	}
 }
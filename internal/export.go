@@ -0,0 +1,469 @@
+package flashdown
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+
+	"github.com/lugu/flashdown/internal/sqlite"
+)
+
+// Export formats.
+const (
+	FormatAnkiText = "anki" // text file imported with Anki's File > Import
+	FormatCSV      = "csv"
+	FormatTSV      = "tsv"
+	FormatApkg     = "apkg" // Anki package with the media files
+)
+
+// ExportFormats lists the formats supported by Export.
+var ExportFormats = []string{FormatAnkiText, FormatCSV, FormatTSV, FormatApkg}
+
+// Anki note types created by the export.
+const (
+	ankiBasicModel    = 1700000000001
+	ankiReversedModel = 1700000000002
+	ankiClozeModel    = 1700000000003
+)
+
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// exportNote is a note of a deck: a Markdown card with the cards it
+// generates.
+type exportNote struct {
+	model int64  // one of the Anki note types
+	front string // Markdown of the question or of the cloze deletions
+	back  string // Markdown of the answer, optional for cloze deletions
+	tags  []string
+	id    string
+	ords  []int // template of each card
+}
+
+// exportNotes groups the cards of a deck by note.
+func exportNotes(deck *Deck) []exportNote {
+	notes := make([]exportNote, 0, len(deck.Cards))
+	for i, card := range deck.Cards {
+		if i > 0 && card.IsSibling(deck.Cards[i-1]) {
+			note := &notes[len(notes)-1]
+			note.ords = append(note.ords, exportOrd(card))
+			continue
+		}
+		note := exportNote{
+			model: ankiBasicModel,
+			front: card.Question,
+			back:  card.Answer,
+			tags:  card.Tags,
+			id:    card.ID,
+			ords:  []int{exportOrd(card)},
+		}
+		if card.Cloze != 0 {
+			note.model = ankiClozeModel
+			note.front = card.text
+			note.back = trim(strings.TrimPrefix(card.Answer,
+				revealCloze(card.text, card.Cloze)))
+		} else if i+1 < len(deck.Cards) && deck.Cards[i+1].Reversed &&
+			card.IsSibling(deck.Cards[i+1]) {
+			note.model = ankiReversedModel
+		}
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+// exportDeckName returns the name of a deck in Anki: its title, or its file
+// name without the extension so that importing it back doesn't add another
+// one.
+func exportDeckName(deck *Deck) string {
+	if deck.Settings.Title != "" {
+		return deck.Settings.Title
+	}
+	return strings.TrimSuffix(deck.Name, path.Ext(deck.Name))
+}
+
+// exportOrd returns the Anki template of a card.
+func exportOrd(card Card) int {
+	if card.Cloze != 0 {
+		return card.Cloze - 1
+	} else if card.Reversed {
+		return 1
+	}
+	return 0
+}
+
+// Export writes the decks to w in one of the ExportFormats.
+func Export(w io.Writer, format string, decks ...*Deck) error {
+	switch format {
+	case FormatAnkiText:
+		return ExportAnkiText(w, decks...)
+	case FormatCSV:
+		return ExportCSV(w, ',', decks...)
+	case FormatTSV:
+		return ExportCSV(w, '\t', decks...)
+	case FormatApkg:
+		return ExportApkg(w, decks...)
+	}
+	return fmt.Errorf("Unknown export format: %s", format)
+}
+
+// ExportCSV writes the notes of the decks with the columns question, answer
+// and tags. Fields are in Markdown: two-sided cards start with "<->" and
+// cloze deletions are kept in the question.
+func ExportCSV(w io.Writer, comma rune, decks ...*Deck) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	writer.Write([]string{"question", "answer", "tags"})
+	for _, deck := range decks {
+		for _, note := range exportNotes(deck) {
+			question := note.front
+			if note.model == ankiReversedModel {
+				question = reverseMarker + " " + question
+			}
+			writer.Write([]string{question, note.back,
+				strings.Join(note.tags, " ")})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportAnkiText writes the notes of the decks as a tab separated text file
+// with HTML fields which Anki imports with File > Import. The columns are
+// the note type, the deck, the two fields of the note and the tags.
+func ExportAnkiText(w io.Writer, decks ...*Deck) error {
+	models := map[int64]string{
+		ankiBasicModel:    "Basic",
+		ankiReversedModel: "Basic (and reversed card)",
+		ankiClozeModel:    "Cloze",
+	}
+	fmt.Fprint(w, "#separator:tab\n#html:true\n#notetype column:1\n#deck column:2\n#tags column:5\n")
+	writer := csv.NewWriter(w)
+	writer.Comma = '\t'
+	for _, deck := range decks {
+		for _, note := range exportNotes(deck) {
+			front, err := markdownToHTML(note.front)
+			if err != nil {
+				return err
+			}
+			back, err := markdownToHTML(note.back)
+			if err != nil {
+				return err
+			}
+			writer.Write([]string{models[note.model], exportDeckName(deck),
+				front, back, strings.Join(note.tags, " ")})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// markdownToHTML converts a field to HTML.
+func markdownToHTML(md string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(md), &buf); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// ankiID returns a stable identifier for an Anki object.
+func ankiID(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64()%(1<<40)) + 1<<40
+}
+
+// ankiChecksum returns the checksum of the first field of a note used by
+// Anki to find duplicates.
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
+
+// ankiMedia collects the media files of an Anki package.
+type ankiMedia struct {
+	names map[string]string // media name by deck and path
+	files map[string][]byte // content by media name
+}
+
+// add reads an image of a deck and returns its media name, or "" if the
+// image can't be read.
+func (m *ankiMedia) add(deck *Deck, src string) string {
+	key := deck.Name + "\x00" + src
+	if name, ok := m.names[key]; ok {
+		return name
+	}
+	if deck.MediaReader == nil || isURL(src) {
+		return ""
+	}
+	file, err := url.PathUnescape(src)
+	if err != nil {
+		file = src
+	}
+	r, err := deck.MediaReader(file)
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(file)
+	for i := 1; ; i++ {
+		if existing, ok := m.files[name]; !ok || bytes.Equal(existing, data) {
+			break
+		}
+		name = fmt.Sprintf("%d-%s", i, path.Base(file))
+	}
+	m.names[key] = name
+	m.files[name] = data
+	return name
+}
+
+// html converts a field to HTML and replaces the paths of the images with
+// their media names.
+func (m *ankiMedia) html(deck *Deck, md string) (string, error) {
+	field, err := markdownToHTML(md)
+	if err != nil {
+		return "", err
+	}
+	return htmlImage.ReplaceAllStringFunc(field, func(img string) string {
+		match := htmlImage.FindStringSubmatch(img)
+		src := html.UnescapeString(match[1])
+		if name := m.add(deck, src); name != "" {
+			return strings.Replace(img, match[1], html.EscapeString(name), 1)
+		}
+		return img
+	}), nil
+}
+
+// ExportApkg writes the decks as an Anki package including the images of
+// the cards. The cards are new: the progress isn't exported.
+func ExportApkg(w io.Writer, decks ...*Deck) error {
+	now := DefaultClock.Now()
+	media := &ankiMedia{
+		names: make(map[string]string),
+		files: make(map[string][]byte),
+	}
+	ankiDecks := map[string]interface{}{"1": ankiDeckJSON(1, "Default", now)}
+	notes := make([][]interface{}, 0)
+	cards := make([][]interface{}, 0)
+	tags := make(map[string]int)
+	nextID := now.UnixMilli()
+	for _, deck := range decks {
+		name := exportDeckName(deck)
+		did := ankiID("deck:" + name)
+		ankiDecks[fmt.Sprint(did)] = ankiDeckJSON(did, name, now)
+		for _, note := range exportNotes(deck) {
+			front, err := media.html(deck, note.front)
+			if err != nil {
+				return err
+			}
+			back, err := media.html(deck, note.back)
+			if err != nil {
+				return err
+			}
+			guid := note.id
+			if guid == "" {
+				guid = fmt.Sprintf("%s:%d", name, ankiID(note.front))
+			}
+			for _, tag := range note.tags {
+				tags[tag] = 0
+			}
+			sortField := singleLine(htmlTag.ReplaceAllString(front, " "))
+			nid := nextID
+			nextID++
+			noteTags := ""
+			if len(note.tags) != 0 {
+				noteTags = " " + strings.Join(note.tags, " ") + " "
+			}
+			notes = append(notes, []interface{}{nid, guid, note.model,
+				now.Unix(), -1, noteTags, front + ankiFieldSeparator + back,
+				sortField, ankiChecksum(sortField), 0, ""})
+			for _, ord := range note.ords {
+				cards = append(cards, []interface{}{nextID, nid, did, ord,
+					now.Unix(), -1, ankiNew, ankiNew, len(notes), 0, 0, 0,
+					0, 0, 0, 0, 0, ""})
+				nextID++
+			}
+		}
+	}
+
+	col, err := ankiColJSON(ankiDecks, tags)
+	if err != nil {
+		return err
+	}
+	col = append([]interface{}{1, startOfDay(now).Unix(), now.UnixMilli(),
+		now.UnixMilli(), 11, 0, 0, 0}, col...)
+	var collection bytes.Buffer
+	err = sqlite.Write(&collection,
+		sqlite.Table{Name: "col", SQL: ankiColTable, Rows: [][]interface{}{col}},
+		sqlite.Table{Name: "notes", SQL: ankiNotesTable, Rows: notes},
+		sqlite.Table{Name: "cards", SQL: ankiCardsTable, Rows: cards},
+		sqlite.Table{Name: "revlog", SQL: ankiRevlogTable},
+		sqlite.Table{Name: "graves", SQL: ankiGravesTable},
+	)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	create := func(name string) (io.Writer, error) {
+		return archive.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: now,
+		})
+	}
+	f, err := create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(collection.Bytes()); err != nil {
+		return err
+	}
+	names := make(map[string]string, len(media.files))
+	for name, data := range media.files {
+		entry := fmt.Sprint(len(names))
+		names[entry] = name
+		f, err := create(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+	}
+	f, err = create("media")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(names); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// startOfDay returns the midnight before t.
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Tables of an Anki collection (schema 11).
+const (
+	ankiColTable = `CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null)`
+
+	ankiNotesTable = `CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null)`
+
+	ankiCardsTable = `CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null)`
+
+	ankiRevlogTable = `CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null)`
+
+	ankiGravesTable = `CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null)`
+)
+
+type jsonMap = map[string]interface{}
+
+// ankiDeckJSON returns the description of an Anki deck.
+func ankiDeckJSON(id int64, name string, now time.Time) jsonMap {
+	return jsonMap{
+		"id": id, "name": name, "mod": now.Unix(), "usn": -1, "desc": "",
+		"dyn": 0, "conf": 1, "collapsed": false, "browserCollapsed": false,
+		"extendNew": 0, "extendRev": 0,
+		"newToday": []int{0, 0}, "revToday": []int{0, 0},
+		"lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+	}
+}
+
+// ankiModelJSON returns the description of an Anki note type.
+func ankiModelJSON(id int64, name string, kind int, fields []string, templates [][3]string) jsonMap {
+	flds := make([]jsonMap, len(fields))
+	for i, field := range fields {
+		flds[i] = jsonMap{"name": field, "ord": i, "sticky": false,
+			"rtl": false, "font": "Arial", "size": 20, "media": []string{}}
+	}
+	tmpls := make([]jsonMap, len(templates))
+	req := make([]interface{}, len(templates))
+	for i, t := range templates {
+		tmpls[i] = jsonMap{"name": t[0], "ord": i, "qfmt": t[1], "afmt": t[2],
+			"did": nil, "bqfmt": "", "bafmt": ""}
+		req[i] = []interface{}{i, "any", []int{i}}
+	}
+	model := jsonMap{
+		"id": id, "name": name, "type": kind, "mod": 0, "usn": -1,
+		"sortf": 0, "did": 1, "flds": flds, "tmpls": tmpls, "tags": []string{},
+		"vers": []int{}, "req": req,
+		"css":       ".card { font-family: arial; font-size: 20px; text-align: center; }\n.cloze { font-weight: bold; color: blue; }",
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+	}
+	if kind == ankiCloze {
+		delete(model, "req")
+	}
+	return model
+}
+
+// ankiColJSON returns the JSON columns of the col table: conf, models,
+// decks, dconf and tags.
+func ankiColJSON(decks jsonMap, tags map[string]int) ([]interface{}, error) {
+	const answer = "{{FrontSide}}\n\n<hr id=answer>\n\n"
+	models := jsonMap{
+		fmt.Sprint(ankiBasicModel): ankiModelJSON(ankiBasicModel,
+			"Basic (flashdown)", ankiStandard, []string{"Front", "Back"},
+			[][3]string{{"Card 1", "{{Front}}", answer + "{{Back}}"}}),
+		fmt.Sprint(ankiReversedModel): ankiModelJSON(ankiReversedModel,
+			"Basic and reversed (flashdown)", ankiStandard, []string{"Front", "Back"},
+			[][3]string{
+				{"Card 1", "{{Front}}", answer + "{{Back}}"},
+				{"Card 2", "{{Back}}", answer + "{{Front}}"},
+			}),
+		fmt.Sprint(ankiClozeModel): ankiModelJSON(ankiClozeModel,
+			"Cloze (flashdown)", ankiCloze, []string{"Text", "Back Extra"},
+			[][3]string{{"Cloze", "{{cloze:Text}}", "{{cloze:Text}}<br>\n{{Back Extra}}"}}),
+	}
+	conf := jsonMap{
+		"activeDecks": []int{1}, "curDeck": 1, "newSpread": 0,
+		"collapseTime": 1200, "timeLim": 0, "estTimes": true, "dueCounts": true,
+		"curModel": nil, "nextPos": 1, "sortType": "noteFld",
+		"sortBackwards": false, "addToCur": true,
+	}
+	dconf := jsonMap{"1": jsonMap{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60,
+		"autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+		"new": jsonMap{
+			"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500,
+			"separate": true, "order": 1, "perDay": 20, "bury": false,
+		},
+		"lapse": jsonMap{
+			"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8,
+			"leechAction": 0,
+		},
+		"rev": jsonMap{
+			"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "minSpace": 1,
+			"ivlFct": 1, "maxIvl": 36500, "bury": false, "hardFactor": 1.2,
+		},
+	}}
+	columns := make([]interface{}, 0, 5)
+	for _, value := range []interface{}{conf, models, decks, dconf, tags} {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, string(data))
+	}
+	return columns, nil
+}
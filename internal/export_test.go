@@ -0,0 +1,162 @@
+package flashdown
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const exportDeck = `---
+title: Geography
+---
+
+## France <!-- id: fr -->
+tags: europe
+
+![map](images/france.png)
+
+Paris
+
+## <-> chat
+
+cat
+
+## The capital of {{c1::Italy}} is {{c3::Rome::city}}.
+
+Since 1871.
+`
+
+func newExportDeck(t *testing.T) (*Deck, string) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "geo.md")
+	if err := os.WriteFile(filename, []byte(exportDeck), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "images", "france.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deck, err := NewDeckFromFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deck, dir
+}
+
+func TestExportCSV(t *testing.T) {
+	deck, _ := newExportDeck(t)
+	var buf bytes.Buffer
+	if err := Export(&buf, FormatTSV, deck); err != nil {
+		t.Fatal(err)
+	}
+	reader := csv.NewReader(&buf)
+	reader.Comma = '\t'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"question", "answer", "tags"},
+		{"France", "![map](images/france.png)\n\nParis", "europe"},
+		{"<-> chat", "cat", ""},
+		{"The capital of {{c1::Italy}} is {{c3::Rome::city}}.", "Since 1871.", ""},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Invalid records: %q", records)
+	}
+}
+
+func TestExportAnkiText(t *testing.T) {
+	deck, _ := newExportDeck(t)
+	var buf bytes.Buffer
+	if err := Export(&buf, FormatAnkiText, deck); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitN(buf.String(), "\n", 6)
+	if lines[0] != "#separator:tab" || lines[1] != "#html:true" {
+		t.Errorf("Invalid header: %q", lines[:5])
+	}
+	reader := csv.NewReader(strings.NewReader(lines[5]))
+	reader.Comma = '\t'
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"Basic", "Geography", "<p>France</p>", "<p><img src=\"images/france.png\" alt=\"map\"></p>\n<p>Paris</p>", "europe"},
+		{"Basic (and reversed card)", "Geography", "<p>chat</p>", "<p>cat</p>", ""},
+		{"Cloze", "Geography", "<p>The capital of {{c1::Italy}} is {{c3::Rome::city}}.</p>", "<p>Since 1871.</p>", ""},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Invalid records: %q", records)
+	}
+	if err := Export(&buf, "pdf", deck); err == nil {
+		t.Errorf("Unknown format accepted")
+	}
+}
+
+func TestExportApkg(t *testing.T) {
+	deck, dir := newExportDeck(t)
+	apkg := filepath.Join(dir, "geo.apkg")
+	f, err := os.Create(apkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ExportApkg(f, deck); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Import the package back.
+	output := filepath.Join(dir, "import")
+	if err := os.Mkdir(output, 0755); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ImportAnki(apkg, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Files) != 1 || result.Notes != 3 || result.Media != 1 {
+		t.Fatalf("Invalid import: %+v", result)
+	}
+	if data, err := os.ReadFile(filepath.Join(output, "france.png")); err != nil || string(data) != "png" {
+		t.Errorf("Invalid media: %q, %v", data, err)
+	}
+	imported, err := NewDeckFromFile(result.Files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Title() != "Geography" || len(imported.Cards) != len(deck.Cards) {
+		t.Fatalf("Invalid deck: %s, %d cards", imported.Title(), len(imported.Cards))
+	}
+	for i, card := range imported.Cards {
+		original := deck.Cards[i]
+		if card.Question != original.Question || card.Cloze != original.Cloze ||
+			card.Reversed != original.Reversed {
+			t.Errorf("%d: invalid card: %+v", i, card)
+		}
+	}
+	if card := imported.Cards[0]; !card.HasTag("europe") ||
+		card.Answer != "![](france.png)\n\nParis" {
+		t.Errorf("Invalid card: %+v", card)
+	}
+}
+
+func TestExportDeckName(t *testing.T) {
+	deck, _ := newExportDeck(t)
+	if name := exportDeckName(deck); name != "Geography" {
+		t.Errorf("Invalid name: %s", name)
+	}
+	deck.Settings.Title = ""
+	if name := exportDeckName(deck); name != "geo" {
+		t.Errorf("Invalid name: %s", name)
+	}
+}
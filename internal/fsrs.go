@@ -0,0 +1,188 @@
+package flashdown
+
+import (
+	"math"
+	"time"
+)
+
+// Rating is the grade used by FSRS to evaluate an answer.
+type Rating int
+
+const (
+	Again Rating = 1 // Forgot the answer.
+	Hard  Rating = 2 // Recalled the answer with serious difficulty.
+	Good  Rating = 3 // Recalled the answer after some hesitation.
+	Easy  Rating = 4 // Recalled the answer perfectly.
+)
+
+// Rating maps the 0-5 score of SM-2 onto the FSRS ratings.
+func (s Score) Rating() Rating {
+	switch {
+	case s < CorrectDifficult:
+		return Again
+	case s == CorrectDifficult:
+		return Hard
+	case s == CorrectEasy:
+		return Good
+	default:
+		return Easy
+	}
+}
+
+const (
+	fsrsDecay  = -0.5
+	fsrsFactor = 19.0 / 81.0 // 0.9^(1/fsrsDecay) - 1
+
+	minimumDifficulty = 1.0
+	maximumDifficulty = 10.0
+	minimumStability  = 0.01
+)
+
+// FSRSParameters configures the FSRS algorithm.
+type FSRSParameters struct {
+	Weights          [19]float64
+	RequestRetention float64 // probability of recall when the card is due.
+	MaximumInterval  int     // in days.
+}
+
+// DefaultFSRSParameters are the default parameters of FSRS v5.
+var DefaultFSRSParameters = FSRSParameters{
+	Weights: [19]float64{
+		0.40255, 1.18385, 3.173, 15.69105, 7.1949, 0.5345, 1.4604,
+		0.0046, 1.54575, 0.1192, 1.01925, 1.9395, 0.11, 0.29605,
+		2.2698, 0.2315, 2.9898, 0.51655, 0.6621,
+	},
+	RequestRetention: 0.9,
+	MaximumInterval:  36500,
+}
+
+// Retrievability returns the probability to recall a card at a given time.
+// It returns 0 for cards never reviewed with FSRS.
+func (c *Meta) Retrievability(now time.Time) float64 {
+	if c.Stability <= 0 || c.LastTime.IsZero() {
+		return 0
+	}
+	return forgettingCurve(daysBetween(c.LastTime, now), c.Stability)
+}
+
+// FSRSScheduler implements FSRS v5.
+// See https://github.com/open-spaced-repetition/fsrs4anki/wiki/The-Algorithm
+type FSRSScheduler struct {
+	Parameters FSRSParameters
+}
+
+// NewFSRSScheduler returns a FSRS scheduler with the default parameters.
+func NewFSRSScheduler() *FSRSScheduler {
+	return &FSRSScheduler{DefaultFSRSParameters}
+}
+
+func (a *FSRSScheduler) Name() string {
+	return "fsrs"
+}
+
+// Schedule implements Scheduler. Cards only known by SM-2 are converted on
+// their first review.
+func (a *FSRSScheduler) Schedule(c Meta, s Score, now time.Time) Meta {
+	p := a.Parameters
+	if c.Stability <= 0 {
+		c.initFSRS()
+	}
+	rating := s.Rating()
+	if c.Stability <= 0 {
+		c.Stability = p.initialStability(rating)
+		c.Difficulty = p.initialDifficulty(rating)
+	} else {
+		elapsed := daysBetween(c.LastTime, now)
+		retrievability := forgettingCurve(elapsed, c.Stability)
+		if elapsed < 1 {
+			c.Stability = p.shortTermStability(c.Stability, rating)
+		} else if rating == Again {
+			c.Stability = p.forgetStability(c.Difficulty, c.Stability, retrievability)
+		} else {
+			c.Stability = p.recallStability(c.Difficulty, c.Stability, retrievability, rating)
+		}
+		c.Difficulty = p.nextDifficulty(c.Difficulty, rating)
+	}
+	c.LastTime = now
+	if rating == Again {
+		c.Repetition = 0
+		c.NextTime = now
+		return c
+	}
+	c.Repetition++
+	c.NextTime = now.AddDate(0, 0, p.interval(c.Stability))
+	return c
+}
+
+// initFSRS converts the SM-2 state into an initial FSRS state. The
+// stability is estimated from the last SM-2 interval and the difficulty
+// from the easiness. Cards which were never successfully reviewed are left
+// untouched and treated as new cards.
+func (c *Meta) initFSRS() {
+	if c.Repetition == 0 {
+		return
+	}
+	days := NewSM2Scheduler().interval(c.Repetition, c.Easiness)
+	c.Stability = float64(days)
+	c.Difficulty = clamp(5+(defaultEasiness-float64(c.Easiness))*5,
+		minimumDifficulty, maximumDifficulty)
+	if c.LastTime.IsZero() {
+		c.LastTime = c.NextTime.AddDate(0, 0, -days)
+	}
+}
+
+func (p FSRSParameters) initialStability(r Rating) float64 {
+	return math.Max(p.Weights[r-1], minimumStability)
+}
+
+func (p FSRSParameters) initialDifficulty(r Rating) float64 {
+	d := p.Weights[4] - math.Exp(p.Weights[5]*float64(r-1)) + 1
+	return clamp(d, minimumDifficulty, maximumDifficulty)
+}
+
+func (p FSRSParameters) nextDifficulty(d float64, r Rating) float64 {
+	delta := -p.Weights[6] * float64(r-3)
+	d = d + delta*(10-d)/9 // linear damping
+	d = p.Weights[7]*p.initialDifficulty(Easy) + (1-p.Weights[7])*d
+	return clamp(d, minimumDifficulty, maximumDifficulty)
+}
+
+func (p FSRSParameters) recallStability(d, s, r float64, rating Rating) float64 {
+	hardPenalty, easyBonus := 1.0, 1.0
+	if rating == Hard {
+		hardPenalty = p.Weights[15]
+	} else if rating == Easy {
+		easyBonus = p.Weights[16]
+	}
+	return s * (1 + math.Exp(p.Weights[8])*(11-d)*math.Pow(s, -p.Weights[9])*
+		(math.Exp((1-r)*p.Weights[10])-1)*hardPenalty*easyBonus)
+}
+
+func (p FSRSParameters) forgetStability(d, s, r float64) float64 {
+	next := p.Weights[11] * math.Pow(d, -p.Weights[12]) *
+		(math.Pow(s+1, p.Weights[13]) - 1) * math.Exp((1-r)*p.Weights[14])
+	return math.Max(math.Min(next, s), minimumStability)
+}
+
+func (p FSRSParameters) shortTermStability(s float64, r Rating) float64 {
+	return s * math.Exp(p.Weights[17]*(float64(r-3)+p.Weights[18]))
+}
+
+// interval returns the number of days until the retrievability drops to
+// the requested retention.
+func (p FSRSParameters) interval(s float64) int {
+	days := s / fsrsFactor * (math.Pow(p.RequestRetention, 1/fsrsDecay) - 1)
+	return int(clamp(math.Round(days), 1, float64(p.MaximumInterval)))
+}
+
+func forgettingCurve(elapsedDays, stability float64) float64 {
+	return math.Pow(1+fsrsFactor*elapsedDays/stability, fsrsDecay)
+}
+
+func daysBetween(from, to time.Time) float64 {
+	return math.Max(to.Sub(from).Hours()/24, 0)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Min(math.Max(v, lo), hi)
+}
@@ -0,0 +1,80 @@
+package flashdown
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScoreRating(t *testing.T) {
+	expected := []Rating{Again, Again, Again, Hard, Good, Easy}
+	for s, r := range expected {
+		if Score(s).Rating() != r {
+			t.Errorf("%d: %d instead of %d", s, Score(s).Rating(), r)
+		}
+	}
+}
+
+func TestFSRSReview(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	var card Card
+	scheduler := NewFSRSScheduler()
+	meta := *NewMeta(card, now)
+	meta = scheduler.Schedule(meta, CorrectEasy, now)
+	if meta.Stability != DefaultFSRSParameters.Weights[Good-1] {
+		t.Errorf("Invalid stability: %f", meta.Stability)
+	}
+	if meta.Difficulty < minimumDifficulty || meta.Difficulty > maximumDifficulty {
+		t.Errorf("Invalid difficulty: %f", meta.Difficulty)
+	}
+	if !meta.NextTime.After(now) {
+		t.Errorf("Invalid next time: %v", meta.NextTime)
+	}
+	for i := 0; i < 5; i++ {
+		stability, difficulty := meta.Stability, meta.Difficulty
+		now = meta.NextTime
+		meta = scheduler.Schedule(meta, CorrectEasy, now)
+		if meta.Stability <= stability {
+			t.Errorf("%d: stability should raise: %f", i, meta.Stability)
+		}
+		if meta.Difficulty > difficulty {
+			t.Errorf("%d: difficulty should not raise: %f", i, meta.Difficulty)
+		}
+		r := meta.Retrievability(meta.NextTime)
+		if r < 0.85 || r > 0.95 {
+			t.Errorf("%d: invalid retrievability: %f", i, r)
+		}
+	}
+	stability, difficulty := meta.Stability, meta.Difficulty
+	now = meta.NextTime
+	meta = scheduler.Schedule(meta, TotalBlackout, now)
+	if meta.Stability >= stability {
+		t.Errorf("Stability should drop: %f", meta.Stability)
+	}
+	if meta.Difficulty <= difficulty {
+		t.Errorf("Difficulty should raise: %f", meta.Difficulty)
+	}
+	if meta.Repetition != 0 || !meta.NextTime.Equal(now) {
+		t.Errorf("Invalid reset: %d, %v", meta.Repetition, meta.NextTime)
+	}
+}
+
+func TestFSRSFromSM2(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	scheduler := NewFSRSScheduler()
+	meta := Meta{
+		NextTime:   now,
+		Repetition: 2,
+		Easiness:   defaultEasiness,
+	}
+	meta = scheduler.Schedule(meta, CorrectEasy, now)
+	if meta.Stability <= SecondRepetitionDelay {
+		t.Errorf("Stability should be above the SM-2 interval: %f",
+			meta.Stability)
+	}
+	if meta.Repetition != 3 {
+		t.Errorf("Invalid repetition: %d", meta.Repetition)
+	}
+	if !meta.NextTime.After(now.AddDate(0, 0, SecondRepetitionDelay)) {
+		t.Errorf("Invalid next time: %v", meta.NextTime)
+	}
+}
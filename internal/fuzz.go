@@ -0,0 +1,91 @@
+package flashdown
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// fuzz spreads the due dates of the cards reviewed together so that they
+// don't come due on the same day forever.
+type fuzz struct {
+	enabled bool
+	balance bool       // pick the least loaded day of the window
+	rand    *rand.Rand // nil for the global source
+}
+
+// fuzzRange returns the number of days an interval can be moved by: none
+// under 3 days, then about 15%, 10% and 5% of the intervals over 7 and 20
+// days.
+func fuzzRange(interval int) int {
+	var delta float64
+	switch {
+	case interval < 3:
+		return 0
+	case interval < 7:
+		delta = 0.15 * float64(interval)
+	case interval < 20:
+		delta = 0.1 * float64(interval)
+	default:
+		delta = 0.05 * float64(interval)
+	}
+	return int(math.Max(1, math.Round(delta)))
+}
+
+func (f fuzz) intn(n int) int {
+	if f.rand == nil {
+		return rand.Intn(n)
+	}
+	return f.rand.Intn(n)
+}
+
+// spread returns an interval within the fuzz range of interval. With the
+// load balancer, the day with the fewest cards due is chosen, load[i]
+// being the number of cards due in i days and covering the whole range.
+// Ties are broken randomly.
+func (f fuzz) spread(interval int, load []int) int {
+	delta := fuzzRange(interval)
+	if delta == 0 {
+		return interval
+	}
+	low, high := interval-delta, interval+delta
+	if !f.balance {
+		return low + f.intn(high-low+1)
+	}
+	best, ties := low, 1
+	for day := low + 1; day <= high; day++ {
+		switch {
+		case load[day] < load[best]:
+			best, ties = day, 1
+		case load[day] == load[best]:
+			// Each of the least loaded days has the same chance.
+			ties++
+			if f.intn(ties) == 0 {
+				best = day
+			}
+		}
+	}
+	return best
+}
+
+// apply moves the due date of a card just reviewed. decks are used to
+// compute the number of cards due each day.
+func (f fuzz) apply(m *Meta, now time.Time, decks []*Deck) {
+	if !f.enabled {
+		return
+	}
+	interval := daysFrom(now, m.NextTime)
+	delta := fuzzRange(interval)
+	if delta == 0 {
+		return
+	}
+	var load []int
+	if f.balance {
+		load = Forecast(decks, now, interval+delta+1)
+		// The card itself isn't part of the load.
+		if load[interval] > 0 {
+			load[interval]--
+		}
+	}
+	m.NextTime = m.NextTime.AddDate(0, 0, f.spread(interval, load)-interval)
+}
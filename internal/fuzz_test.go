@@ -0,0 +1,93 @@
+package flashdown
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFuzzRange(t *testing.T) {
+	ranges := map[int]int{0: 0, 1: 0, 2: 0, 3: 1, 6: 1, 10: 1, 20: 1, 60: 3, 365: 18}
+	for interval, expected := range ranges {
+		if delta := fuzzRange(interval); delta != expected {
+			t.Errorf("%d days: invalid range %d", interval, delta)
+		}
+	}
+}
+
+func TestFuzzSpread(t *testing.T) {
+	draw := func(seed int64) []int {
+		f := fuzz{enabled: true, rand: rand.New(rand.NewSource(seed))}
+		intervals := make([]int, 100)
+		for i := range intervals {
+			intervals[i] = f.spread(60, nil)
+		}
+		return intervals
+	}
+	intervals := draw(1)
+	seen := make(map[int]bool)
+	for _, interval := range intervals {
+		if interval < 57 || interval > 63 {
+			t.Fatalf("Interval out of range: %d", interval)
+		}
+		seen[interval] = true
+	}
+	if len(seen) != 7 {
+		t.Errorf("Intervals not spread: %v", seen)
+	}
+	if fmt.Sprint(draw(1)) != fmt.Sprint(intervals) {
+		t.Errorf("Same seed, different intervals")
+	}
+
+	// The load balancer picks the least loaded day.
+	f := fuzz{enabled: true, balance: true, rand: rand.New(rand.NewSource(1))}
+	load := []int{0, 0, 0, 0, 0, 4, 3, 1, 0}
+	if interval := f.spread(6, load); interval != 7 {
+		t.Errorf("Invalid balanced interval: %d", interval)
+	}
+	load = []int{0, 0, 0, 0, 0, 1, 5, 1}
+	seen = make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		seen[f.spread(6, load)] = true
+	}
+	if len(seen) != 2 || !seen[5] || !seen[7] {
+		t.Errorf("Invalid ties: %v", seen)
+	}
+}
+
+func TestGameLoadBalance(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	var md strings.Builder
+	for i := 0; i < 9; i++ {
+		fmt.Fprintf(&md, "## question %d\nanswer %d\n", i, i)
+	}
+	deck := newMemoryDeck(t, clock, md.String())
+	deck.Scheduler = NewSM2Scheduler()
+	clock.Add(time.Minute)
+	opts := GameOptions{
+		Clock:       clock,
+		Fuzz:        true,
+		LoadBalance: true,
+		Rand:        rand.New(rand.NewSource(1)),
+	}
+	game := NewGameWithOptions(opts, deck)
+	for !game.IsFinished() {
+		game.Review(PerfectRecall)
+	}
+	// The cards are due 6 days later, give or take a day.
+	forecast := Forecast([]*Deck{deck}, clock.Now(), 9)
+	if fmt.Sprint(forecast) != "[0 0 0 0 0 3 3 3 0]" {
+		t.Errorf("Invalid forecast: %v", forecast)
+	}
+	logs, err := deck.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, log := range logs {
+		if log.Interval < 5 || log.Interval > 7 {
+			t.Errorf("%d: invalid interval: %f", i, log.Interval)
+		}
+	}
+}
@@ -2,19 +2,103 @@ package flashdown
 
 import (
 	"fmt"
+	"log"
+	"math/rand"
+	"sort"
 	"time"
 )
 
 // Game represents a learning session.
 type Game struct {
 	cards    []Card
+	due      []time.Time // when cards[i] can be asked, zero if now
 	decks    []*Deck
+	clock    Clock
+	asked    time.Time // when the current card was shown
 	index    int
 	success  int
 	total    int
 	finished bool
+	undo     []undoEntry // most recent last
+	learning learning
+	// suspendLeeches suspends the cards when they become leeches.
+	suspendLeeches bool
+	leech          *Card // suspended by the last review
+	fuzz           fuzz
 }
 
+// learning reinserts the failed cards in the session.
+type learning struct {
+	enabled bool
+	gap     int
+	steps   []time.Duration
+	fails   map[*Meta]int // failures of each card during the session
+}
+
+// undoEntry is the state of a game before a review or a skip.
+type undoEntry struct {
+	index    int
+	success  int
+	finished bool
+	asked    time.Time
+	meta     *Meta      // meta data of the card reviewed, nil for a skip
+	before   Meta       // value of meta before the review
+	log      *ReviewLog // history entry of the review, nil if not saved
+	cards    []Card     // cards of the session, nil if unchanged
+	due      []time.Time
+	requeued bool // the card was inserted again in the session
+}
+
+// GameOptions configures how a Game selects its cards.
+type GameOptions struct {
+	// CardsNb is the maximum number of cards to use, see NewGame.
+	CardsNb int
+	// Clock tells which cards are due and when cards are reviewed. If
+	// nil, DefaultClock is used.
+	Clock Clock
+	// Filter selects the cards of the session. If nil, all cards are
+	// selected.
+	Filter func(Card) bool
+	// Learning asks the failed cards again later in the session, which
+	// only finishes when every card has been answered correctly.
+	Learning bool
+	// LearningGap is the number of cards asked before a failed card is
+	// asked again. If zero, DefaultLearningGap is used.
+	LearningGap int
+	// LearningSteps are the delays before a failed card is asked again,
+	// one per failure, the last one being repeated (ex: 1m, 10m). A card
+	// is asked earlier if no other card remains. If set, LearningGap is
+	// ignored.
+	LearningSteps []time.Duration
+	// NewCardsPerDay is the maximum number of new cards studied each day
+	// across the decks, including those studied earlier in the day. If
+	// zero, there is no limit. See also DeckSettings.NewCardsPerDay.
+	NewCardsPerDay int
+	// ReviewsPerDay is the maximum number of cards reviewed each day
+	// across the decks, the most overdue first. If zero, there is no
+	// limit. See also DeckSettings.ReviewsPerDay.
+	ReviewsPerDay int
+	// Order sorts the cards before the session is truncated to CardsNb
+	// cards: OrderRandom, OrderOverdue, OrderRetrievability or
+	// OrderRoundRobin. If empty, the cards are shuffled.
+	Order string
+	// SuspendLeeches suspends the cards which become leeches during the
+	// session, see DeckSettings.SuspendLeeches.
+	SuspendLeeches bool
+	// Fuzz moves the due dates of the reviewed cards by a few days so
+	// that the cards learned together don't stay together.
+	Fuzz bool
+	// LoadBalance makes the fuzz pick the day with the fewest cards due
+	// across the decks instead of a random day.
+	LoadBalance bool
+	// Rand is the source of the fuzz. If nil, the global source is used.
+	Rand *rand.Rand
+}
+
+// DefaultLearningGap is the number of cards asked before a failed card is
+// asked again in the learning mode.
+const DefaultLearningGap = 3
+
 const (
 	ALL_CARDS       = -1
 	CARDS_TO_REVIEW = 0
@@ -23,15 +107,24 @@ const (
 // NewGameFromFiles reads the markdown files to instantiate a Game. cardsNb
 // represents the maximum number of cards to use.
 func NewGameFromFiles(cardsNb int, files []string) (*Game, error) {
+	decks, err := NewDecksFromFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	return NewGame(cardsNb, decks...), nil
+}
+
+// NewDecksFromFiles reads the decks of the markdown files.
+func NewDecksFromFiles(files []string) ([]*Deck, error) {
 	decks := make([]*Deck, len(files))
 	for i, file := range files {
 		deck, err := NewDeckFromFile(file)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to load %s: %v", file, err)
+			return nil, fmt.Errorf("Failed to load %s: %w", file, err)
 		}
 		decks[i] = deck
 	}
-	return NewGame(cardsNb, decks...), nil
+	return decks, nil
 }
 
 // NewGame returns a game given a set of markdown files.
@@ -41,25 +134,76 @@ func NewGameFromFiles(cardsNb int, files []string) (*Game, error) {
 // If cardsNb is a strictly positive number, up to cardsNb from the cards to
 // review will be used.
 func NewGame(cardsNb int, decks ...*Deck) *Game {
+	return NewGameWithOptions(GameOptions{CardsNb: cardsNb}, decks...)
+}
+
+// NewGameWithOptions returns a game given a set of decks and options.
+func NewGameWithOptions(opts GameOptions, decks ...*Deck) *Game {
+	cardsNb := opts.CardsNb
 	game := &Game{
-		cards: make([]Card, 0),
-		decks: decks,
+		cards:          make([]Card, 0),
+		decks:          decks,
+		clock:          opts.Clock,
+		suspendLeeches: opts.SuspendLeeches,
+		fuzz: fuzz{
+			enabled: opts.Fuzz,
+			balance: opts.LoadBalance,
+			rand:    opts.Rand,
+		},
 	}
+	if game.clock == nil {
+		game.clock = DefaultClock
+	}
+	now := game.clock.Now()
+	var newToday, reviewsToday int // studied today across the decks
 	for i, deck := range decks {
 		var cards []Card
 		if cardsNb == ALL_CARDS {
 			cards = deck.Cards
 		} else {
-			cards = deck.SelectBefore(time.Now())
+			cards = deck.SelectBefore(now)
+		}
+		cards = filterCards(cards, opts.Filter)
+		if cardsNb != ALL_CARDS && (opts.NewCardsPerDay > 0 ||
+			opts.ReviewsPerDay > 0 || deck.Settings.NewCardsPerDay > 0 ||
+			deck.Settings.ReviewsPerDay > 0) {
+			newCards, reviews := deck.StudiedToday(now)
+			newToday += newCards
+			reviewsToday += reviews
+			cards = limitCards(cards,
+				dailyLimit(deck.Settings.NewCardsPerDay, newCards),
+				dailyLimit(deck.Settings.ReviewsPerDay, reviews))
 		}
+		selected := len(filterCards(deck.Cards, opts.Filter))
 		game.cards = append(game.cards, cards...)
-		game.success += len(deck.Cards) - len(cards)
-		game.total += len(deck.Cards)
+		game.success += selected - len(cards)
+		game.total += selected
 		game.decks[i] = deck
 	}
-	game.cards = ShuffleCards(game.cards)
+	game.cards = OrderCards(game.cards, opts.Order, now)
+	if cardsNb != ALL_CARDS {
+		game.cards = limitCards(game.cards,
+			dailyLimit(opts.NewCardsPerDay, newToday),
+			dailyLimit(opts.ReviewsPerDay, reviewsToday))
+	}
 	if cardsNb > 0 && len(game.cards) > cardsNb {
-		game.cards = game.cards[0:cardsNb]
+		// The reviews are kept before the new cards.
+		sort.SliceStable(game.cards, func(i, j int) bool {
+			return game.cards[i].Meta.State() > game.cards[j].Meta.State()
+		})
+		game.cards = OrderCards(game.cards[0:cardsNb], opts.Order, now)
+	}
+	game.cards = SeparateSiblings(game.cards)
+	game.due = make([]time.Time, len(game.cards))
+	game.asked = game.clock.Now()
+	game.learning = learning{
+		enabled: opts.Learning,
+		gap:     opts.LearningGap,
+		steps:   opts.LearningSteps,
+		fails:   make(map[*Meta]int),
+	}
+	if game.learning.gap <= 0 {
+		game.learning.gap = DefaultLearningGap
 	}
 	return game
 }
@@ -80,11 +224,129 @@ func (g *Game) Answer() string {
 	return g.cards[g.index].Answer
 }
 
+// MarkedQuestion returns the question like Question, with the blanked
+// cloze deletion between ClozeBlankStart and ClozeBlankEnd.
+func (g *Game) MarkedQuestion() string {
+	if len(g.cards) == 0 {
+		return "No cards"
+	}
+	return markedQuestion(g.cards[g.index])
+}
+
+// MarkedAnswer returns the answer like Answer, with the revealed cloze
+// deletion between ClozeRevealStart and ClozeRevealEnd.
+func (g *Game) MarkedAnswer() string {
+	if len(g.cards) == 0 {
+		return "No cards"
+	}
+	return markedAnswer(g.cards[g.index])
+}
+
+// DeckDescription returns the description of the deck of the current card
+// from its settings, if any.
+func (g *Game) DeckDescription() string {
+	if len(g.cards) == 0 || g.cards[g.index].deck == nil {
+		return ""
+	}
+	return g.cards[g.index].deck.Settings.Description
+}
+
 func (g *Game) DeckName() string {
 	if len(g.cards) == 0 {
 		return "zero"
 	}
-	return g.cards[g.index].DeckName
+	card := g.cards[g.index]
+	if card.deck != nil {
+		return card.deck.Title()
+	}
+	return card.DeckName
+}
+
+// filterCards returns the cards selected by filter. A nil filter selects all
+// cards.
+func filterCards(cards []Card, filter func(Card) bool) []Card {
+	if filter == nil {
+		return cards
+	}
+	selected := make([]Card, 0, len(cards))
+	for _, card := range cards {
+		if filter(card) {
+			selected = append(selected, card)
+		}
+	}
+	return selected
+}
+
+// dailyLimit returns how many cards can still be studied today given the
+// limit per day and the cards already studied. It returns -1 if limit is
+// zero, meaning no limit.
+func dailyLimit(limit, studied int) int {
+	if limit <= 0 {
+		return -1
+	}
+	if studied >= limit {
+		return 0
+	}
+	return limit - studied
+}
+
+// limitCards keeps up to newCards new cards, in order, and up to reviews
+// cards in review, the most overdue first. The order of the cards is
+// preserved. A negative limit means no limit.
+func limitCards(cards []Card, newCards, reviews int) []Card {
+	if newCards < 0 && reviews < 0 {
+		return cards
+	}
+	overdue := make([]*Meta, 0, len(cards))
+	for _, card := range cards {
+		if card.Meta.State() == StateReview {
+			overdue = append(overdue, card.Meta)
+		}
+	}
+	kept := make(map[*Meta]bool, len(overdue))
+	if reviews >= 0 && reviews < len(overdue) {
+		sort.SliceStable(overdue, func(i, j int) bool {
+			return overdue[i].NextTime.Before(overdue[j].NextTime)
+		})
+		overdue = overdue[:reviews]
+	}
+	for _, meta := range overdue {
+		kept[meta] = true
+	}
+	selected := make([]Card, 0, len(cards))
+	for _, card := range cards {
+		if card.Meta.State() == StateNew {
+			if newCards == 0 {
+				continue
+			}
+			newCards--
+		} else if !kept[card.Meta] {
+			continue
+		}
+		selected = append(selected, card)
+	}
+	return selected
+}
+
+// SuspendedLeech returns the card suspended by the last review because it
+// became a leech, see GameOptions.SuspendLeeches.
+func (g *Game) SuspendedLeech() (Card, bool) {
+	if g.leech == nil {
+		return Card{}, false
+	}
+	return *g.leech, true
+}
+
+// suspendLeech suspends the card if it is a leech and if the leeches of
+// its deck are suspended.
+func (g *Game) suspendLeech(card Card) {
+	if card.deck == nil || !card.Meta.IsLeech(card.deck.LeechThreshold()) {
+		return
+	}
+	if g.suspendLeeches || card.deck.Settings.SuspendLeeches {
+		card.Meta.Suspended = true
+		g.leech = &card
+	}
 }
 
 // Score represents how easly one responded to a question.
@@ -105,13 +367,41 @@ const (
 	PerfectRecall Score = iota
 )
 
+// Review updates the current card according to the score, records the
+// answer in the history of its deck and moves to the next card.
 func (g *Game) Review(s Score) {
+	g.leech = nil
 	if g.index < len(g.cards) {
+		undo := g.undoEntry()
 		if s >= 3 {
 			g.success++
 		}
-		g.cards[g.index].Meta.Review(s)
+		now := g.clock.Now()
+		card := g.cards[g.index]
+		undo.meta, undo.before = card.Meta, *card.Meta
+		card.Review(s, now)
+		g.fuzz.apply(card.Meta, now, g.decks)
+		if card.Meta.Lapses > undo.before.Lapses {
+			g.suspendLeech(card)
+		}
+		if g.learning.enabled && s < 3 && !card.Meta.Suspended {
+			undo.cards = append([]Card{}, g.cards...)
+			undo.due = append([]time.Time{}, g.due...)
+			undo.requeued = true
+			g.requeue(card, now)
+		}
+		if card.deck != nil {
+			entry := newReviewLog(undo.before, *card.Meta, s, now, now.Sub(g.asked))
+			if err := card.deck.AppendHistory(entry); err != nil {
+				log.Printf("Failed to save history of %s: %v", card.DeckName, err)
+			} else {
+				undo.log = &entry
+			}
+		}
+		g.undo = append(g.undo, undo)
 		g.index++
+		g.asked = now
+		g.nextDue(now)
 	}
 	if g.index == len(g.cards) {
 		g.index = 0
@@ -122,12 +412,61 @@ func (g *Game) Review(s Score) {
 func (g *Game) Previous() {
 	if g.index > 0 {
 		g.index--
+		g.asked = g.clock.Now()
 	}
 }
 
 func (g *Game) Skip() {
 	if g.index < len(g.cards) {
+		g.undo = append(g.undo, g.undoEntry())
+		g.index++
+		g.asked = g.clock.Now()
+		g.nextDue(g.asked)
+	}
+	if g.index == len(g.cards) {
+		g.index = 0
+		g.finished = true
+	}
+}
+
+// Suspend stops asking the current card, in this session and the next
+// ones, until it is unsuspended (see Meta.Unsuspend), and moves to the next
+// card.
+func (g *Game) Suspend() {
+	g.setAside(func(meta *Meta) {
+		meta.Suspended = true
+	})
+}
+
+// Bury stops asking the current card until the next day and moves to the
+// next card.
+func (g *Game) Bury() {
+	now := g.clock.Now()
+	g.setAside(func(meta *Meta) {
+		meta.Bury(now)
+	})
+}
+
+// setAside updates the current card with set and removes it from the rest
+// of the session. It can be undone like a skip.
+func (g *Game) setAside(set func(*Meta)) {
+	if g.index < len(g.cards) {
+		undo := g.undoEntry()
+		card := g.cards[g.index]
+		undo.meta, undo.before = card.Meta, *card.Meta
+		undo.cards = append([]Card{}, g.cards...)
+		undo.due = append([]time.Time{}, g.due...)
+		set(card.Meta)
+		for i := len(g.cards) - 1; i > g.index; i-- {
+			if g.cards[i].Meta == card.Meta {
+				g.cards = append(g.cards[:i], g.cards[i+1:]...)
+				g.due = append(g.due[:i], g.due[i+1:]...)
+			}
+		}
+		g.undo = append(g.undo, undo)
 		g.index++
+		g.asked = g.clock.Now()
+		g.nextDue(g.asked)
 	}
 	if g.index == len(g.cards) {
 		g.index = 0
@@ -135,6 +474,96 @@ func (g *Game) Skip() {
 	}
 }
 
+// requeue inserts a failed card later in the session: after a few cards
+// or at the end once its learning step has elapsed.
+func (g *Game) requeue(card Card, now time.Time) {
+	fails := g.learning.fails[card.Meta]
+	g.learning.fails[card.Meta] = fails + 1
+	position := g.index + 1 + g.learning.gap
+	var due time.Time
+	if steps := g.learning.steps; len(steps) != 0 {
+		if fails >= len(steps) {
+			fails = len(steps) - 1
+		}
+		position = len(g.cards)
+		due = now.Add(steps[fails])
+	}
+	if position > len(g.cards) {
+		position = len(g.cards)
+	}
+	g.cards = append(g.cards[:position], append([]Card{card}, g.cards[position:]...)...)
+	g.due = append(g.due[:position], append([]time.Time{due}, g.due[position:]...)...)
+}
+
+// nextDue moves forward the first card which can be asked if the current
+// card is waiting for its learning step. The current card is kept if no
+// other card can be asked.
+func (g *Game) nextDue(now time.Time) {
+	if g.index >= len(g.cards) || !g.due[g.index].After(now) {
+		return
+	}
+	for i := g.index + 1; i < len(g.cards); i++ {
+		if !g.due[i].After(now) {
+			card, due := g.cards[i], g.due[i]
+			copy(g.cards[g.index+1:i+1], g.cards[g.index:i])
+			copy(g.due[g.index+1:i+1], g.due[g.index:i])
+			g.cards[g.index], g.due[g.index] = card, due
+			return
+		}
+	}
+}
+
+// undoEntry returns the current state of the game.
+func (g *Game) undoEntry() undoEntry {
+	return undoEntry{
+		index:    g.index,
+		success:  g.success,
+		finished: g.finished,
+		asked:    g.asked,
+	}
+}
+
+// CanUndo returns true if a review, a skip, a suspension or a burial can be
+// undone.
+func (g *Game) CanUndo() bool {
+	return len(g.undo) != 0
+}
+
+// Undo cancels the last review, skip, suspension or burial: the meta data
+// of the card, the success count and the position in the session are
+// restored, and the review is removed from the history. It returns false if
+// there is nothing to undo.
+func (g *Game) Undo() bool {
+	if len(g.undo) == 0 {
+		return false
+	}
+	undo := g.undo[len(g.undo)-1]
+	g.undo = g.undo[:len(g.undo)-1]
+	g.leech = nil
+	if undo.cards != nil {
+		g.cards, g.due = undo.cards, undo.due
+	}
+	if undo.requeued {
+		g.learning.fails[undo.meta]--
+	}
+	if undo.meta != nil {
+		*undo.meta = undo.before
+	}
+	if undo.log != nil {
+		card := g.cards[undo.index]
+		cancel := *undo.log
+		cancel.Undo = true
+		if err := card.deck.AppendHistory(cancel); err != nil {
+			log.Printf("Failed to save history of %s: %v", card.DeckName, err)
+		}
+	}
+	g.index = undo.index
+	g.success = undo.success
+	g.finished = undo.finished
+	g.asked = undo.asked
+	return true
+}
+
 func (g *Game) Progress() (current, total int) {
 	return g.index + 1, len(g.cards)
 }
@@ -0,0 +1,544 @@
+package flashdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryAccessor is a DeckAccessor which keeps the deck in memory.
+type memoryAccessor struct {
+	name  string
+	cards string
+	meta  bytes.Buffer
+	logs  bytes.Buffer
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (m *memoryAccessor) DeckName() string {
+	return m.name
+}
+
+func (m *memoryAccessor) CardsReader() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewBufferString(m.cards)), nil
+}
+
+func (m *memoryAccessor) MetaReader() (io.ReadCloser, error) {
+	if m.meta.Len() == 0 {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(m.meta.Bytes())), nil
+}
+
+func (m *memoryAccessor) MetaWriter() (io.WriteCloser, error) {
+	m.meta.Reset()
+	return nopWriteCloser{&m.meta}, nil
+}
+
+func (m *memoryAccessor) HistoryReader() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(m.logs.Bytes())), nil
+}
+
+func (m *memoryAccessor) HistoryWriter() (io.WriteCloser, error) {
+	return nopWriteCloser{&m.logs}, nil
+}
+
+func newMemoryDeck(t *testing.T, clock Clock, cards string) *Deck {
+	deck, err := NewDeckWithClock(&memoryAccessor{name: "memory.md", cards: cards}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return deck
+}
+
+func TestGameWithClock(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+`)
+	deck.Scheduler = NewSM2Scheduler()
+	opts := GameOptions{CardsNb: CARDS_TO_REVIEW, Clock: clock}
+
+	// Everything is due the first day, nothing the next days.
+	clock.Add(time.Minute)
+	for day := 0; day < FirstRepetitionDelay; day++ {
+		game := NewGameWithOptions(opts, deck)
+		current, total := game.Progress()
+		if day == 0 && total != 2 || day != 0 && !game.IsFinished() {
+			t.Errorf("day %d: %d/%d cards", day, current, total)
+		}
+		for !game.IsFinished() {
+			game.Review(CorrectEasy)
+		}
+		clock.Add(24 * time.Hour)
+	}
+	for _, card := range deck.Cards {
+		due := time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC).
+			AddDate(0, 0, FirstRepetitionDelay)
+		if !card.Meta.NextTime.Equal(due) {
+			t.Errorf("Invalid next time: %v", card.Meta.NextTime)
+		}
+	}
+	// The cards are due again after FirstRepetitionDelay days.
+	toReview, total := deck.Stats()
+	if toReview != 0 || total != 2 {
+		t.Errorf("Invalid stats: %d/%d", toReview, total)
+	}
+	clock.Set(deck.Cards[0].Meta.NextTime.Add(time.Minute))
+	toReview, _ = deck.Stats()
+	if toReview != 2 {
+		t.Errorf("Invalid stats: %d", toReview)
+	}
+}
+
+func TestGameHistory(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+`)
+	deck.Scheduler = NewSM2Scheduler()
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	clock.Add(10 * time.Second)
+	game.Review(TotalBlackout)
+	clock.Add(20 * time.Second)
+	game.Review(PerfectRecall)
+
+	logs, err := deck.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("Invalid history: %d", len(logs))
+	}
+	if logs[0].Score != TotalBlackout || logs[0].Duration != 10*time.Second ||
+		logs[0].Interval != 0 {
+		t.Errorf("Invalid log: %v", logs[0])
+	}
+	if logs[1].Score != PerfectRecall || logs[1].Duration != 20*time.Second ||
+		logs[1].Interval != FirstRepetitionDelay ||
+		!logs[1].Time.Equal(clock.Now()) {
+		t.Errorf("Invalid log: %v", logs[1])
+	}
+}
+
+func TestReadCorruptedHistory(t *testing.T) {
+	history := `{"Hash":1,"Time":"2024-01-01T10:00:00Z","Score":5}
+garbage
+{"Hash":2,"Time":"2024-01-01T10:01:00Z","Score":5}
+{"Hash":3,"Time":"2024-01`
+	logs, skipped, err := readHistory(strings.NewReader(history))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[1].Hash != 2 {
+		t.Errorf("Invalid history: %v", logs)
+	}
+	if fmt.Sprint(skipped) != "[2 4]" {
+		t.Errorf("Invalid skipped lines: %v", skipped)
+	}
+}
+
+func TestStudiedToday(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+`)
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	game.Review(TotalBlackout)
+	game.Review(PerfectRecall)
+	game.Save()
+	if n, r := deck.StudiedToday(clock.Now()); n != 2 || r != 0 {
+		t.Errorf("Day 1: %d new cards, %d reviews", n, r)
+	}
+	// The failed card is relearned the next day: it isn't new anymore.
+	clock.Add(24 * time.Hour)
+	game = NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	for !game.IsFinished() {
+		game.Review(PerfectRecall)
+	}
+	game.Save()
+	if n, r := deck.StudiedToday(clock.Now()); n != 0 || r == 0 {
+		t.Errorf("Day 2: %d new cards, %d reviews", n, r)
+	}
+}
+
+func TestGameUndo(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+`)
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	if game.CanUndo() || game.Undo() {
+		t.Fatal("Nothing to undo")
+	}
+	first := game.Question()
+	before := *game.cards[0].Meta
+	game.Review(PerfectRecall)
+	game.Skip()
+	if !game.IsFinished() || game.Success() != 50 {
+		t.Fatalf("Invalid game: finished %v, success %v", game.IsFinished(), game.Success())
+	}
+
+	// Undo the skip.
+	if !game.Undo() || game.IsFinished() {
+		t.Fatal("Skip not undone")
+	}
+	if current, _ := game.Progress(); current != 2 {
+		t.Errorf("Invalid position: %d", current)
+	}
+	// Undo the review.
+	if !game.Undo() || game.Question() != first || game.Success() != 0 {
+		t.Fatalf("Review not undone: %s, %v", game.Question(), game.Success())
+	}
+	if *game.cards[0].Meta != before {
+		t.Errorf("Meta not restored: %+v", game.cards[0].Meta)
+	}
+	if game.CanUndo() {
+		t.Errorf("Empty undo stack expected")
+	}
+	logs, err := deck.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("Undone review in the history: %v", logs)
+	}
+
+	game.Review(IncorrectEasy)
+	logs, _ = deck.History()
+	if len(logs) != 1 || logs[0].Score != IncorrectEasy {
+		t.Errorf("Invalid history: %v", logs)
+	}
+}
+
+func TestGameLearning(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+## question 3
+answer 3
+`)
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{
+		Clock:       clock,
+		Learning:    true,
+		LearningGap: 1,
+	}, deck)
+	failed := game.Question()
+	game.Review(TotalBlackout)
+	if _, total := game.Progress(); total != 4 {
+		t.Errorf("Failed card not reinserted: %d cards", total)
+	}
+	game.Review(PerfectRecall)
+	if game.Question() != failed {
+		t.Errorf("Failed card not asked after one card: %s", game.Question())
+	}
+	// Undo the second failure: the card isn't reinserted.
+	game.Review(IncorrectEasy)
+	if _, total := game.Progress(); total != 5 {
+		t.Errorf("Failed card not reinserted: %d cards", total)
+	}
+	game.Undo()
+	if _, total := game.Progress(); total != 4 || game.Question() != failed {
+		t.Errorf("Invalid undo: %d cards, %s", total, game.Question())
+	}
+	for !game.IsFinished() {
+		game.Review(PerfectRecall)
+	}
+	if current, total := game.Progress(); current != 1 || total != 4 || game.Success() != 100 {
+		t.Errorf("Invalid progress: %d/%d %v%%", current, total, game.Success())
+	}
+}
+
+func TestGameLearningSteps(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+`)
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{
+		Clock:         clock,
+		Learning:      true,
+		LearningSteps: []time.Duration{time.Minute, 10 * time.Minute},
+	}, deck)
+	first := game.Question()
+	game.Review(TotalBlackout) // due in 1 minute
+	second := game.Question()
+	game.Review(TotalBlackout) // due in 1 minute
+	if game.Question() != first {
+		t.Fatalf("Invalid question: %s", game.Question())
+	}
+	clock.Add(2 * time.Minute)
+	game.Review(TotalBlackout) // second failure: due in 10 minutes
+	if game.Question() != second {
+		t.Fatalf("Invalid question: %s", game.Question())
+	}
+	game.Review(PerfectRecall)
+	// No other card: the card waiting for its step is asked early.
+	if game.IsFinished() || game.Question() != first {
+		t.Fatalf("Invalid question: %s", game.Question())
+	}
+	game.Review(PerfectRecall)
+	if !game.IsFinished() {
+		t.Errorf("Session not finished")
+	}
+
+	// A card waiting for its step lets the due cards pass.
+	now := clock.Now()
+	game.cards = deck.Cards[:2]
+	game.due = []time.Time{now.Add(time.Minute), now}
+	game.index = 0
+	game.nextDue(now)
+	if game.cards[0].Question != "question 2" || !game.due[1].Equal(now.Add(time.Minute)) {
+		t.Errorf("Due card not moved forward: %v", game.cards)
+	}
+}
+
+func TestResumeGame(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	cards := `
+## question 1
+answer 1
+## question 2
+answer 2
+## question 3
+answer 3
+`
+	deck := newMemoryDeck(t, clock, cards)
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{Clock: clock, Learning: true}, deck)
+	game.Review(TotalBlackout)
+	game.Review(PerfectRecall)
+	var buf bytes.Buffer
+	if err := WriteSession(&buf, game.Session()); err != nil {
+		t.Fatal(err)
+	}
+	session, err := ReadSession(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Remaining() != 2 {
+		t.Errorf("Invalid remaining cards: %d", session.Remaining())
+	}
+
+	resumed, err := ResumeGame(session, deck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Question() != game.Question() || resumed.Success() != game.Success() {
+		t.Errorf("Invalid question: %s", resumed.Question())
+	}
+	current, total := resumed.Progress()
+	if c, tt := game.Progress(); current != c || total != tt {
+		t.Errorf("Invalid progress: %d/%d", current, total)
+	}
+
+	// The cards removed from the deck are skipped: remove the card answered
+	// correctly.
+	removed := strings.TrimPrefix(game.cards[1].Question, "question ")
+	edited := newMemoryDeck(t, clock, strings.Replace(cards,
+		fmt.Sprintf("## question %s\nanswer %s\n", removed, removed), "", 1))
+	edited.Name = deck.Name
+	resumed, err = ResumeGame(session, edited)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, total := resumed.Progress(); total != 3 {
+		t.Errorf("Removed card not skipped: %d cards", total)
+	}
+	if resumed.Question() != game.Question() {
+		t.Errorf("Invalid question: %s", resumed.Question())
+	}
+
+	if _, err := ResumeGame(session); !errors.Is(err, ErrSessionChanged) {
+		t.Errorf("Missing deck accepted: %v", err)
+	}
+	for !game.IsFinished() {
+		game.Review(PerfectRecall)
+	}
+	if _, err := ResumeGame(game.Session(), deck); !errors.Is(err, ErrSessionChanged) {
+		t.Errorf("Finished session resumed: %v", err)
+	}
+}
+
+func TestResumeSameNames(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	// Decks of different directories have the same name.
+	deck1 := newMemoryDeck(t, clock, "## question 1\nanswer 1\n")
+	deck2 := newMemoryDeck(t, clock, "## question 2\nanswer 2\n")
+	deck3 := newMemoryDeck(t, clock, "## question 3\nanswer 3\n")
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{Clock: clock}, deck1, deck2)
+	session := game.Session()
+	for _, decks := range [][]*Deck{{deck1, deck2}, {deck2, deck1}} {
+		resumed, err := ResumeGame(session, decks...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resumed.decks[0] != deck1 || resumed.decks[1] != deck2 {
+			t.Errorf("Invalid decks: %v", resumed.decks)
+		}
+	}
+	// An edited deck is found at the same position.
+	resumed, err := ResumeGame(session, deck1, deck3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.decks[0] != deck1 || resumed.decks[1] != deck3 {
+		t.Errorf("Invalid decks: %v", resumed.decks)
+	}
+	if _, err := ResumeGame(session, deck3); !errors.Is(err, ErrSessionChanged) {
+		t.Errorf("Missing deck accepted: %v", err)
+	}
+}
+
+func TestDailyLimits(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `---
+new_cards_per_day: 2
+---
+## question 1
+answer 1
+## question 2
+answer 2
+## question 3
+answer 3
+## question 4
+answer 4
+## question 5
+answer 5
+`)
+	deck.Scheduler = NewSM2Scheduler()
+	clock.Add(time.Minute)
+	states := func(game *Game) (newCards, reviews int) {
+		for _, card := range game.cards {
+			if card.Meta.State() == StateNew {
+				newCards++
+			} else {
+				reviews++
+			}
+		}
+		return newCards, reviews
+	}
+
+	// The new cards studied today count against the limit.
+	game := NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	if newCards, reviews := states(game); newCards != 2 || reviews != 0 {
+		t.Fatalf("Invalid first session: %d new, %d reviews", newCards, reviews)
+	}
+	first := game.cards[0].Meta
+	game.Review(PerfectRecall)
+	clock.Add(time.Minute)
+	game = NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	if newCards, reviews := states(game); newCards != 1 || reviews != 0 {
+		t.Fatalf("Invalid second session: %d new, %d reviews", newCards, reviews)
+	}
+	game.Review(PerfectRecall)
+	clock.Add(time.Minute)
+	if game = NewGameWithOptions(GameOptions{Clock: clock}, deck); !game.IsFinished() {
+		t.Fatalf("Limit exceeded: %d cards", len(game.cards))
+	}
+
+	// The reviews are limited across the decks, the most overdue first.
+	clock.Add(FirstRepetitionDelay * 24 * time.Hour)
+	opts := GameOptions{Clock: clock, NewCardsPerDay: 1, ReviewsPerDay: 1}
+	game = NewGameWithOptions(opts, deck)
+	if newCards, reviews := states(game); newCards != 1 || reviews != 1 {
+		t.Fatalf("Invalid limits: %d new, %d reviews", newCards, reviews)
+	}
+	for _, card := range game.cards {
+		if card.Meta.State() == StateReview && card.Meta != first {
+			t.Errorf("Invalid review: %s", card.Question)
+		}
+	}
+
+	// The reviews are kept when the session is truncated.
+	game = NewGameWithOptions(GameOptions{Clock: clock, CardsNb: 2}, deck)
+	if newCards, reviews := states(game); newCards != 0 || reviews != 2 {
+		t.Errorf("Invalid session: %d new, %d reviews", newCards, reviews)
+	}
+}
+
+func TestGameSuspendAndBury(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+## question 3
+answer 3
+`)
+	clock.Add(time.Minute)
+	opts := GameOptions{Clock: clock, Learning: true}
+	game := NewGameWithOptions(opts, deck)
+	buried, suspended := game.cards[0].Meta, game.cards[1].Meta
+	game.Bury()
+	// A failed card asked again later in the session is removed once
+	// suspended.
+	game.Review(TotalBlackout)
+	if _, total := game.Progress(); total != 4 {
+		t.Fatalf("Failed card not requeued: %d cards", total)
+	}
+	game.Previous()
+	game.Suspend()
+	if game.Undo(); game.cards[game.index].Meta != suspended || suspended.Suspended {
+		t.Fatalf("Suspension not undone")
+	}
+	game.Suspend()
+	if _, total := game.Progress(); total != 3 || !suspended.Suspended {
+		t.Fatalf("Invalid suspension: %d cards", total)
+	}
+	if !buried.IsBuried(clock.Now()) || len(deck.HiddenCards(clock.Now())) != 2 {
+		t.Fatalf("Invalid burial: %+v", buried)
+	}
+
+	// The buried card is asked the next day, the suspended one isn't.
+	clock.Add(24 * time.Hour)
+	cards := deck.SelectBefore(clock.Now())
+	for _, card := range cards {
+		if card.Meta == suspended {
+			t.Errorf("Suspended card selected")
+		}
+	}
+	if len(cards) != 2 {
+		t.Errorf("Invalid cards: %d", len(cards))
+	}
+	hidden := deck.HiddenCards(clock.Now())
+	if len(hidden) != 1 || hidden[0].Meta != suspended {
+		t.Fatalf("Invalid hidden cards: %d", len(hidden))
+	}
+	suspended.Unsuspend()
+	if cards := deck.SelectBefore(clock.Now()); len(cards) != 3 {
+		t.Errorf("Unsuspended card not selected: %d", len(cards))
+	}
+}
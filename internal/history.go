@@ -0,0 +1,111 @@
+package flashdown
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ReviewLog records an answer to a card. The history of a deck is an append
+// only list of ReviewLog.
+type ReviewLog struct {
+	Hash             Digest
+	Time             time.Time     // when the answer was given
+	Score            Score         // how easly one responded
+	PreviousInterval float64       // in days, before the review
+	Interval         float64       // in days, after the review
+	Duration         time.Duration // time spent answering
+	// New is true for the first review of a card.
+	New bool `json:",omitempty"`
+	// Undo cancels the review of the same card at the same time.
+	Undo bool `json:",omitempty"`
+}
+
+// newReviewLog returns the log of a review given the meta data before and
+// after the review.
+func newReviewLog(before, after Meta, s Score, now time.Time, spent time.Duration) ReviewLog {
+	return ReviewLog{
+		Hash:             after.Hash,
+		Time:             now,
+		Score:            s,
+		PreviousInterval: before.Interval(),
+		Interval:         after.Interval(),
+		Duration:         spent,
+		New:              before.IsNew(),
+	}
+}
+
+// readHistory parses a history made of one JSON object per line. The
+// reviews cancelled by an undo are removed. The lines which can't be
+// decoded, like a last line cut by a crash, are skipped: their numbers are
+// returned.
+func readHistory(r io.Reader) (logs []ReviewLog, skipped []int, err error) {
+	logs = make([]ReviewLog, 0)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var log ReviewLog
+		if err := json.Unmarshal(scanner.Bytes(), &log); err != nil {
+			skipped = append(skipped, line)
+			continue
+		}
+		logs = append(logs, log)
+	}
+	return removeUndone(logs), skipped, scanner.Err()
+}
+
+// removeUndone removes the undo entries and the reviews they cancel.
+func removeUndone(logs []ReviewLog) []ReviewLog {
+	kept := make([]ReviewLog, 0, len(logs))
+	for _, log := range logs {
+		if !log.Undo {
+			kept = append(kept, log)
+			continue
+		}
+		for i := len(kept) - 1; i >= 0; i-- {
+			if kept[i].Hash == log.Hash && kept[i].Time.Equal(log.Time) {
+				kept = append(kept[:i], kept[i+1:]...)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// writeHistory appends the logs to w, one JSON object per line.
+func writeHistory(w io.Writer, logs ...ReviewLog) error {
+	encoder := json.NewEncoder(w)
+	for _, log := range logs {
+		if err := encoder.Encode(log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// studiedToday counts the cards studied on the day of now: the new cards
+// reviewed for the first time and the other cards reviewed, including the
+// cards relearned after a failure. A card reviewed several times is counted
+// once. The reviews logged before ReviewLog.New existed are counted as
+// reviews.
+func studiedToday(logs []ReviewLog, now time.Time) (newCards, reviews int) {
+	today := startOfDay(now)
+	tomorrow := endOfDay(now)
+	seen := make(map[Digest]bool)
+	for _, log := range logs {
+		t := log.Time.In(now.Location())
+		if t.Before(today) || !t.Before(tomorrow) || seen[log.Hash] {
+			continue
+		}
+		seen[log.Hash] = true
+		if log.New {
+			newCards++
+		} else {
+			reviews++
+		}
+	}
+	return newCards, reviews
+}
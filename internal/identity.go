@@ -0,0 +1,191 @@
+package flashdown
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cardID matches the optional identifier of a card: <!-- id: 4f2a9c1e -->
+var cardID = regexp.MustCompile(`<!--\s*id:\s*([\w-]+)\s*-->`)
+
+// matchThreshold is the minimum similarity between two questions to consider
+// one to be an edit of the other.
+const matchThreshold = 0.8
+
+// minMatchLength is the minimum length of the questions matched by
+// similarity.
+const minMatchLength = 10
+
+// parseCardID removes the identifier from a card description and returns it.
+func parseCardID(md string) (string, string) {
+	match := cardID.FindStringSubmatchIndex(md)
+	if match == nil {
+		return md, ""
+	}
+	id := md[match[2]:match[3]]
+	return md[:match[0]] + md[match[1]:], id
+}
+
+// newCardID returns a random identifier.
+func newCardID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// InsertIDs adds an identifier at the end of the questions which don't have
+// one. It returns the updated deck and the number of identifiers added.
+func InsertIDs(md string) (string, int, error) {
+	_, body, err := parseFrontMatter(md)
+	if err != nil {
+		return md, 0, err
+	}
+	lines := strings.Split(md, "\n")
+	sheets, indexes := splitCards(body)
+	added := 0
+	for i, sheet := range sheets {
+		if !strings.HasPrefix(sheet, "##") {
+			continue
+		}
+		if _, id := parseCardID(sheet); id != "" {
+			continue
+		}
+		line := strings.TrimRight(lines[indexes[i]], " \t\r")
+		lines[indexes[i]] = fmt.Sprintf("%s <!-- id: %s -->", line, newCardID())
+		added++
+	}
+	return strings.Join(lines, "\n"), added, nil
+}
+
+// similarity returns a number between 0 and 1 telling how close two
+// questions are, 1 meaning identical. Under matchThreshold, it may return
+// an upper bound of the similarity.
+func similarity(a, b string) float64 {
+	ra := []rune(strings.ToLower(strings.Join(strings.Fields(a), " ")))
+	rb := []rune(strings.ToLower(strings.Join(strings.Fields(b), " ")))
+	longest, shortest := len(ra), len(rb)
+	if shortest > longest {
+		longest, shortest = shortest, longest
+	}
+	if longest == 0 {
+		return 1
+	}
+	// The edit distance is at least the difference of length.
+	if bound := 1 - float64(longest-shortest)/float64(longest); bound < matchThreshold {
+		return bound
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// matchMeta returns the meta data of a card. It looks for the card digest,
+// then for the digest of its question (for cards which received an
+// identifier since the last save), then for the digests used by older
+// versions: the question as displayed (cloze cards) and the heading with
+// its tags.
+func matchMeta(metaMap MetaMap, card Card) (*Meta, bool) {
+	hashes := []Digest{Hash(card), questionHash(card), textHash(card.Question)}
+	if card.heading != "" {
+		heading := card.heading
+		if card.Cloze != 0 {
+			heading = hideCloze(heading, card.Cloze)
+		}
+		hashes = append(hashes, textHash(heading))
+	}
+	for _, hash := range hashes {
+		if meta, ok := metaMap[hash]; ok {
+			return meta, true
+		}
+	}
+	return nil, false
+}
+
+// rematchMetas reattaches the orphaned meta data to the cards without meta
+// data when their questions are similar: this happens when a question is
+// edited. The candidate pairs are scored once and the best matches are used
+// first. Short questions are never matched: a single letter changes their
+// meaning (ex: house and horse).
+func rematchMetas(cards []Card, orphans MetaMap) {
+	type candidate struct {
+		card  int
+		meta  *Meta
+		score float64
+	}
+	candidates := make([]candidate, 0)
+	for i := range cards {
+		if cards[i].Meta != nil || len([]rune(cards[i].Question)) < minMatchLength {
+			continue
+		}
+		for _, meta := range orphans {
+			if len([]rune(meta.Question)) < minMatchLength {
+				continue
+			}
+			if s := similarity(cards[i].Question, meta.Question); s >= matchThreshold {
+				candidates = append(candidates, candidate{i, meta, s})
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.card != b.card {
+			return a.card < b.card
+		}
+		return a.meta.Hash < b.meta.Hash
+	})
+	for _, c := range candidates {
+		if cards[c.card].Meta != nil || orphans[c.meta.Hash] == nil {
+			continue
+		}
+		delete(orphans, c.meta.Hash)
+		cards[c.card].Meta = c.meta
+	}
+}
+
+// duplicateCards returns the groups of cards which share the same digest.
+func duplicateCards(cards []Card) [][]Card {
+	groups := make(map[Digest][]Card)
+	order := make([]Digest, 0)
+	for _, card := range cards {
+		hash := Hash(card)
+		if len(groups[hash]) == 0 {
+			order = append(order, hash)
+		}
+		groups[hash] = append(groups[hash], card)
+	}
+	duplicates := make([][]Card, 0)
+	for _, hash := range order {
+		if len(groups[hash]) > 1 {
+			duplicates = append(duplicates, groups[hash])
+		}
+	}
+	return duplicates
+}
@@ -0,0 +1,145 @@
+package flashdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCardID(t *testing.T) {
+	cards, err := readCards(bytes.NewBufferString(`
+## question 1 #tag <!-- id: abc-1 -->
+answer 1
+## question 1
+answer 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cards[0].ID != "abc-1" || cards[0].Question != "question 1" {
+		t.Errorf("Invalid card: %q (id: %q)", cards[0].Question, cards[0].ID)
+	}
+	if cards[1].ID != "" {
+		t.Errorf("Unexpected id: %q", cards[1].ID)
+	}
+	if Hash(cards[0]) == Hash(cards[1]) {
+		t.Errorf("The id must be used as digest")
+	}
+}
+
+func TestInsertIDs(t *testing.T) {
+	input := `---
+title: deck
+---
+## question 1
+answer 1
+` + "```" + `
+## not a question
+` + "```" + `
+## question 2 <!-- id: 1234 -->
+answer 2
+`
+	output, added, err := InsertIDs(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 {
+		t.Errorf("Invalid number of ids: %d", added)
+	}
+	lines := strings.Split(output, "\n")
+	if !cardID.MatchString(lines[3]) || cardID.MatchString(lines[7]) {
+		t.Errorf("Invalid output: %s", output)
+	}
+	if _, added, _ := InsertIDs(output); added != 0 {
+		t.Errorf("Ids added twice: %d", added)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	if s := similarity("Main memory reference", "main  memory reference"); s != 1 {
+		t.Errorf("Invalid similarity: %f", s)
+	}
+	if s := similarity("Main memory reference", "Main memory refrence"); s < matchThreshold {
+		t.Errorf("Invalid similarity: %f", s)
+	}
+	if s := similarity("Main memory reference", "Disk seek"); s >= matchThreshold {
+		t.Errorf("Invalid similarity: %f", s)
+	}
+}
+
+func TestRematchEditedQuestion(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	accessor := &memoryAccessor{name: "memory.md", cards: `
+## Main memory reference
+100 ns
+## Disk seek
+10 ms
+`}
+	deck, err := NewDeckWithClock(accessor, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deck.Cards[0].Review(PerfectRecall, clock.Now())
+	deck.Cards[1].Review(PerfectRecall, clock.Now())
+	if err := deck.SaveDeckMeta(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fix a typo, add an identifier and rewrite a question.
+	accessor.cards = `
+## Main memory refrence
+100 ns
+## Disk seek <!-- id: disk -->
+10 ms
+## How long does a disk seek take?
+10 ms
+`
+	deck, err = NewDeckWithClock(accessor, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, repetition := range []int32{1, 1, 0} {
+		if deck.Cards[i].Meta.Repetition != repetition {
+			t.Errorf("%d: invalid repetition: %d", i, deck.Cards[i].Meta.Repetition)
+		}
+	}
+	if deck.Cards[1].Meta.Hash != Hash(deck.Cards[1]) {
+		t.Errorf("The digest must be updated")
+	}
+}
+
+func TestDuplicateCards(t *testing.T) {
+	cards, err := readCards(bytes.NewBufferString(`
+## question 1
+answer 1
+## Question 1?
+answer 2
+## question 2
+answer 3
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	duplicates := duplicateCards(cards)
+	if len(duplicates) != 1 || len(duplicates[0]) != 2 || duplicates[0][1].Line != 4 {
+		t.Errorf("Invalid duplicates: %v", duplicates)
+	}
+}
+
+func TestRematchShortQuestions(t *testing.T) {
+	cards := []Card{
+		{Question: "horse"},
+		{Question: "Main memory refrence"},
+		{Question: "Main memory reference!"},
+	}
+	house := &Meta{Hash: 1, Question: "house"}
+	memory := &Meta{Hash: 2, Question: "Main memory reference"}
+	rematchMetas(cards, MetaMap{1: house, 2: memory})
+	if cards[0].Meta != nil {
+		t.Errorf("Short question matched")
+	}
+	if cards[1].Meta != nil || cards[2].Meta != memory {
+		t.Errorf("Best match not used first")
+	}
+}
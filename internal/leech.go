@@ -0,0 +1,60 @@
+package flashdown
+
+import (
+	"sort"
+)
+
+// DefaultLeechThreshold is the number of lapses after which a card is a
+// leech, unless its deck sets leech_threshold.
+const DefaultLeechThreshold = 8
+
+// Leech is a card failed over and over which should probably be rewritten.
+type Leech struct {
+	Deck      string // name of the deck
+	Line      int    // line of the question in the deck
+	Question  string
+	Lapses    int
+	Suspended bool
+}
+
+// IsLeech returns true if the card lapsed threshold times or more.
+func (c *Meta) IsLeech(threshold int) bool {
+	return threshold > 0 && int(c.Lapses) >= threshold
+}
+
+// LeechThreshold returns the number of lapses after which the cards of the
+// deck are leeches.
+func (d *Deck) LeechThreshold() int {
+	if d.Settings.LeechThreshold > 0 {
+		return d.Settings.LeechThreshold
+	}
+	return DefaultLeechThreshold
+}
+
+// Leeches returns the leeches of the decks, the most lapsed first. If
+// threshold is zero, the threshold of each deck is used.
+func Leeches(threshold int, decks ...*Deck) []Leech {
+	leeches := make([]Leech, 0)
+	for _, deck := range decks {
+		limit := threshold
+		if limit <= 0 {
+			limit = deck.LeechThreshold()
+		}
+		for _, card := range deck.Cards {
+			if !card.Meta.IsLeech(limit) {
+				continue
+			}
+			leeches = append(leeches, Leech{
+				Deck:      deck.Name,
+				Line:      card.Line,
+				Question:  card.Question,
+				Lapses:    int(card.Meta.Lapses),
+				Suspended: card.Meta.Suspended,
+			})
+		}
+	}
+	sort.SliceStable(leeches, func(i, j int) bool {
+		return leeches[i].Lapses > leeches[j].Lapses
+	})
+	return leeches
+}
@@ -0,0 +1,63 @@
+package flashdown
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeeches(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `---
+leech_threshold: 2
+suspend_leeches: true
+---
+## question 1
+answer 1
+`)
+	deck.Scheduler = NewSM2Scheduler()
+	meta := deck.Cards[0].Meta
+	review := func(s Score) *Game {
+		clock.Add(time.Minute)
+		game := NewGameWithOptions(GameOptions{Clock: clock}, deck)
+		if game.IsFinished() {
+			t.Fatalf("No card to review: %+v", meta)
+		}
+		game.Review(s)
+		return game
+	}
+
+	// A new card failed isn't a lapse.
+	review(TotalBlackout)
+	review(PerfectRecall)
+	if meta.Lapses != 0 {
+		t.Fatalf("Invalid lapses: %d", meta.Lapses)
+	}
+	for i := 1; i <= 2; i++ {
+		clock.Set(meta.NextTime)
+		game := review(IncorrectEasy)
+		if int(meta.Lapses) != i || meta.IsLeech(2) != (i == 2) {
+			t.Fatalf("Invalid lapses: %d", meta.Lapses)
+		}
+		if leech, ok := game.SuspendedLeech(); ok != (i == 2) || (ok && leech.Meta != meta) {
+			t.Errorf("Invalid suspended leech: %+v, %v", leech, ok)
+		}
+		if i < 2 {
+			review(PerfectRecall)
+		}
+	}
+	if !meta.Suspended {
+		t.Errorf("Leech not suspended")
+	}
+	clock.Add(24 * time.Hour)
+	if cards := deck.SelectBefore(clock.Now()); len(cards) != 0 {
+		t.Errorf("Suspended card selected")
+	}
+	leeches := Leeches(0, deck)
+	if len(leeches) != 1 || leeches[0].Lapses != 2 || !leeches[0].Suspended ||
+		leeches[0].Line != 5 || leeches[0].Question != "question 1" {
+		t.Errorf("Invalid leeches: %+v", leeches)
+	}
+	if leeches := Leeches(3, deck); len(leeches) != 0 {
+		t.Errorf("Invalid leeches: %+v", leeches)
+	}
+}
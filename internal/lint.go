@@ -0,0 +1,246 @@
+package flashdown
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity of the issues reported by Lint.
+const (
+	SeverityError   = "error"   // the deck can't be loaded
+	SeverityWarning = "warning" // the deck can be loaded
+)
+
+var (
+	// imagePattern matches the path of the Markdown images.
+	imagePattern = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)`)
+	// linkPattern matches the URL of the Markdown links and autolinks.
+	linkPattern = regexp.MustCompile(`(?:[^!]|^)\[[^\]]*\]\(\s*<?(https?://[^)\s>]+)|<(https?://[^>\s]+)>`)
+)
+
+// Issue is a problem found in a deck.
+type Issue struct {
+	File     string
+	Line     int // starts at 1
+	Column   int // byte offset in the line, starts at 1
+	Severity string
+	Message  string
+}
+
+// String formats the issue like compilers do: file:line:column: message.
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", i.File, i.Line, i.Column,
+		i.Severity, i.Message)
+}
+
+// LintOptions enables the checks which access the file system or the
+// network.
+type LintOptions struct {
+	// Dir is the directory used to resolve the relative paths of the
+	// images. Images aren't checked if empty.
+	Dir string
+	// CheckLink returns an error if a link is unreachable. Links aren't
+	// checked if nil.
+	CheckLink func(url string) error
+}
+
+// Lint returns all the issues found in a deck, sorted by line.
+func Lint(deck DeckAccessor, options LintOptions) ([]Issue, error) {
+	reader, err := deck.CardsReader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return lintDeck(deck.DeckName(), string(data), options), nil
+}
+
+// LintFile returns the issues found in a deck file. Images are resolved from
+// the directory of the deck.
+func LintFile(filename string, options LintOptions) ([]Issue, error) {
+	if options.Dir == "" {
+		options.Dir = filepath.Dir(filename)
+	}
+	issues, err := Lint(newFileDeckAccessor(filename), options)
+	for i := range issues {
+		issues[i].File = filename
+	}
+	return issues, err
+}
+
+// CheckURL returns an error if the URL can't be retrieved.
+func CheckURL(link string) error {
+	client := http.Client{Timeout: 10 * time.Second}
+	response, err := client.Head(link)
+	if err == nil && response.StatusCode == http.StatusMethodNotAllowed {
+		response.Body.Close()
+		response, err = client.Get(link)
+	}
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 400 {
+		return errors.New(response.Status)
+	}
+	return nil
+}
+
+func lintDeck(name, md string, options LintOptions) []Issue {
+	issues := make([]Issue, 0)
+	report := func(line, column int, severity, format string, args ...interface{}) {
+		issues = append(issues, Issue{
+			File:     name,
+			Line:     line,
+			Column:   column,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+	settings, body, err := parseFrontMatter(md)
+	if err != nil {
+		report(1, 1, SeverityError, "%s", err)
+		return issues
+	}
+
+	// Cards
+	lines := strings.Split(body, "\n")
+	cards := make([]Card, 0)
+	sheets, indexes := splitCards(body)
+	for i, sheet := range sheets {
+		line := indexes[i] + 1
+		card, err := loadCard(sheet)
+		switch {
+		case err == errCardEmpty:
+			continue
+		case !strings.HasPrefix(trim(sheet), "##"):
+			report(firstTextLine(lines, indexes[i]), 1, SeverityError,
+				"Text outside of a card")
+			continue
+		case err == errQuestionMissing || (err == errInvalidCard && card.Question == ""):
+			report(line, headingColumn(lines[indexes[i]]), SeverityError,
+				"Missing question")
+			continue
+		case err == errInvalidCard:
+			report(line, headingColumn(lines[indexes[i]]), SeverityError,
+				"Empty answer")
+			continue
+		case err == errReverseCloze:
+			report(line, headingColumn(lines[indexes[i]]), SeverityError,
+				"Cloze deletions in a two-sided card")
+			continue
+		}
+		card.Line = line
+		cards = append(cards, expandCard(card, settings)...)
+	}
+	for _, duplicates := range duplicateCards(cards) {
+		for _, card := range duplicates[1:] {
+			if card.IsSibling(duplicates[0]) {
+				continue
+			}
+			report(card.Line, headingColumn(lines[card.Line-1]), SeverityWarning,
+				"Duplicate question (see line %d)", duplicates[0].Line)
+		}
+	}
+
+	// Code blocks, images and links
+	checked := make(map[string]error)
+	fence := -1 // line of the opened code block
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			if fence == -1 {
+				fence = i
+			} else {
+				fence = -1
+			}
+			continue
+		}
+		if fence != -1 {
+			continue
+		}
+		if options.Dir != "" {
+			for _, match := range imagePattern.FindAllStringSubmatchIndex(line, -1) {
+				image := line[match[2]:match[3]]
+				if isURL(image) {
+					continue
+				}
+				path, err := url.PathUnescape(image)
+				if err != nil {
+					path = image
+				}
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(options.Dir, path)
+				}
+				if _, err := os.Stat(path); err != nil {
+					report(i+1, match[2]+1, SeverityError,
+						"Broken image path: %s", image)
+				}
+			}
+		}
+		if options.CheckLink != nil {
+			for _, match := range linkPattern.FindAllStringSubmatchIndex(line, -1) {
+				start, end := match[2], match[3]
+				if start == -1 {
+					start, end = match[4], match[5]
+				}
+				link := line[start:end]
+				err, ok := checked[link]
+				if !ok {
+					err = options.CheckLink(link)
+					checked[link] = err
+				}
+				if err != nil {
+					report(i+1, start+1, SeverityWarning,
+						"Unreachable link: %s (%s)", link, err)
+				}
+			}
+		}
+	}
+	if fence != -1 {
+		report(fence+1, 1, SeverityError, "Unterminated code block")
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Line != issues[j].Line {
+			return issues[i].Line < issues[j].Line
+		}
+		return issues[i].Column < issues[j].Column
+	})
+	return issues
+}
+
+// isURL returns true if path has a scheme like https: or data:.
+func isURL(path string) bool {
+	u, err := url.Parse(path)
+	return err == nil && u.Scheme != ""
+}
+
+// headingColumn returns the column of the question in a heading line.
+func headingColumn(line string) int {
+	loc := splitQuestion.FindStringIndex(line)
+	if loc == nil {
+		return 1
+	}
+	return loc[1] + 1
+}
+
+// firstTextLine returns the first non blank line after index, starting at 1.
+func firstTextLine(lines []string, index int) int {
+	for i := index; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i + 1
+		}
+	}
+	return index + 1
+}
@@ -0,0 +1,77 @@
+package flashdown
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cat.png"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	input := `---
+title: lint
+---
+Some text
+
+## question 1
+![cat](cat.png) ![dog](dog.png)
+## question 2
+
+## question 1?
+answer [link](https://example.com/broken) <https://example.com/ok>
+##
+answer 4
+## <-> {{c1::cloze}}
+` + "```go" + `
+## not a question
+`
+	checked := make([]string, 0)
+	issues := lintDeck("deck.md", input, LintOptions{
+		Dir: dir,
+		CheckLink: func(url string) error {
+			checked = append(checked, url)
+			if url == "https://example.com/broken" {
+				return errors.New("404 Not Found")
+			}
+			return nil
+		},
+	})
+	expected := []string{
+		"deck.md:4:1: error: Text outside of a card",
+		"deck.md:7:24: error: Broken image path: dog.png",
+		"deck.md:8:4: error: Empty answer",
+		"deck.md:10:4: warning: Duplicate question (see line 6)",
+		"deck.md:11:15: warning: Unreachable link: https://example.com/broken (404 Not Found)",
+		"deck.md:12:3: error: Missing question",
+		"deck.md:14:4: error: Cloze deletions in a two-sided card",
+		"deck.md:15:1: error: Unterminated code block",
+	}
+	if len(issues) != len(expected) {
+		t.Errorf("Invalid number of issues: %d", len(issues))
+	}
+	for i, issue := range issues {
+		if i >= len(expected) || issue.String() != expected[i] {
+			t.Errorf("%d: unexpected issue: %s", i, issue)
+		}
+	}
+	if len(checked) != 2 {
+		t.Errorf("Invalid links: %v", checked)
+	}
+}
+
+func TestLintFile(t *testing.T) {
+	issues, err := LintFile("samples/testdata/test-2.md", LintOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) == 0 {
+		t.Fatal("Missing issue")
+	}
+	if issues[0].File != "samples/testdata/test-2.md" || issues[0].Severity != SeverityError {
+		t.Errorf("Invalid issue: %s", issues[0])
+	}
+}
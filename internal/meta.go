@@ -1,8 +1,10 @@
 package flashdown
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"hash/fnv"
 	"io"
 	"io/ioutil"
@@ -24,49 +26,50 @@ const (
 // Meta contains information about the succces of a card.
 type Meta struct {
 	Hash       Digest
-	NextTime   time.Time // next time to ask
-	Repetition int32     // # of success in a row
-	Easiness   float32   // how easy is it
+	NextTime   time.Time  // next time to ask
+	Repetition int32      // # of success in a row
+	Easiness   float32    // how easy is it
+	LastTime   time.Time  // last time asked
+	Stability  float64    `json:",omitempty"` // FSRS: days to drop to 90% recall
+	Difficulty float64    `json:",omitempty"` // FSRS: between 1 and 10
+	Question   string     `json:",omitempty"` // used to follow question edits
+	Lapses     int32      `json:",omitempty"` // # of failures once learned
+	Suspended  bool       `json:",omitempty"` // never asked until unsuspended
+	Buried     *time.Time `json:",omitempty"` // not asked before this time
 }
 
-// NewMeta initialize a new card
-func NewMeta(card Card) *Meta {
+// NewMeta initialize a new card to be asked from now.
+func NewMeta(card Card, now time.Time) *Meta {
 	return &Meta{
 		Hash:       Hash(card),
+		Question:   card.Question,
 		Repetition: 0,
 		Easiness:   defaultEasiness,
-		NextTime:   time.Now(),
+		NextTime:   now,
 	}
 }
 
-// Review updates the card meta data according to the score.
-// See https://en.wikipedia.org/wiki/SuperMemo
-// FirstRepetitionDelay and SecondRepetitionDelay have been
-// modified, originally they were 1 and 6.
-func (c *Meta) Review(s Score) {
-	if s >= 3 {
-		switch c.Repetition {
-		case 0:
-			c.NextTime = time.Now().AddDate(0, 0, FirstRepetitionDelay)
-		case 1:
-			c.NextTime = time.Now().AddDate(0, 0, SecondRepetitionDelay)
-		default:
-			// 6 days per successful repetition
-			sinceLastTime := float64(c.Repetition) * SecondRepetitionDelay
-			days := int(sinceLastTime * float64(c.Easiness))
-			c.NextTime = time.Now().AddDate(0, 0, days)
-		}
+// Bury postpones the card to the next day without changing its schedule.
+func (c *Meta) Bury(now time.Time) {
+	tomorrow := endOfDay(now)
+	c.Buried = &tomorrow
+}
 
-		Q := 5.0 - float32(s)
-		c.Easiness = c.Easiness + 0.1 - Q*0.08 - Q*Q*0.02
-		if c.Easiness < minimumEasiness {
-			c.Easiness = minimumEasiness
-		}
-		c.Repetition++
-	} else {
-		c.Repetition = 0
-		c.NextTime = time.Now()
-	}
+// IsBuried returns true if the card is buried at the time now.
+func (c *Meta) IsBuried(now time.Time) bool {
+	return c.Buried != nil && now.Before(*c.Buried)
+}
+
+// Unsuspend asks the card again: it is neither suspended nor buried
+// anymore.
+func (c *Meta) Unsuspend() {
+	c.Suspended = false
+	c.Buried = nil
+}
+
+// Review updates the card meta data according to the score using SM-2.
+func (c *Meta) Review(s Score, now time.Time) {
+	*c = NewSM2Scheduler().Schedule(*c, s, now)
 }
 
 func strip(s string) string {
@@ -82,27 +85,76 @@ func strip(s string) string {
 	return result.String()
 }
 
-// Hash returns a hash value to index the question. Computed hash is loosy
-// since it ignore non alpha numerical values in order to ignore typos
-// correction.
+// Hash returns a hash value to index the card. Cards with an identifier are
+// indexed by their identifier, the others by their question. Computed hash
+// is loosy since it ignore non alpha numerical values in order to ignore
+// typos correction.
 func Hash(card Card) Digest {
+	if card.ID == "" {
+		return questionHash(card)
+	}
+	h := fnv.New64()
+	fmt.Fprintf(h, "id:%s:%d:%t", card.ID, card.Cloze, card.Reversed)
+	return Digest(h.Sum64())
+}
+
+// questionHash returns the digest of the question of a card. The digest of
+// a cloze card doesn't depend on the other deletions of its note.
+func questionHash(card Card) Digest {
+	if card.Cloze != 0 && card.text != "" {
+		return textHash(clozeDigestText(card.text, card.Cloze))
+	}
+	return textHash(card.Question)
+}
+
+// textHash returns the digest of a text, ignoring its case and anything but
+// letters and digits.
+func textHash(s string) Digest {
 	h := fnv.New64()
-	h.Write([]byte(strip(card.Question)))
+	h.Write([]byte(strip(s)))
 	return Digest(h.Sum64())
 }
 
-func readDB(r io.Reader) ([]Meta, error) {
-	metas := make([]Meta, 0)
-	bytes, err := ioutil.ReadAll(r)
+// readDB reads the meta data file and upgrades it to the current version.
+// An empty file contains no meta data.
+func readDB(r io.Reader) (metaDB, error) {
+	db := metaDB{Version: MetaVersion, Cards: make([]Meta, 0)}
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return db, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return db, nil
+	}
+	if data, err = migrate(data); err != nil {
+		return db, err
 	}
-	err = json.Unmarshal(bytes, &metas)
-	return metas, nil
+	if err := json.Unmarshal(data, &db); err != nil {
+		return db, err
+	}
+	if db.Cards == nil {
+		db.Cards = make([]Meta, 0)
+	}
+	return db, nil
+}
+
+// ValidMeta returns true if data is a meta data file which can be read:
+// only such a file replaces the backup of the meta data. An empty file
+// isn't valid since it would replace the backup with no progress.
+func ValidMeta(data []byte) bool {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return false
+	}
+	_, err := readDB(bytes.NewReader(data))
+	return err == nil
 }
 
-func writeDB(w io.Writer, metas []Meta) error {
-	bytes, err := json.MarshalIndent(metas, "", "    ")
+func writeDB(w io.Writer, db metaDB) error {
+	db.Version = MetaVersion
+	if db.Cards == nil {
+		db.Cards = make([]Meta, 0)
+	}
+	bytes, err := json.MarshalIndent(db, "", "    ")
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package flashdown
 
 import (
 	"bytes"
+	"math"
 	"testing"
 	"time"
 )
@@ -71,89 +72,60 @@ func TestHash(t *testing.T) {
 }
 
 func TestMetaReview(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
 	var card Card
-	meta := NewMeta(card)
+	meta := NewMeta(card, clock.Now())
 	if meta.Repetition != 0 {
 		t.Errorf("Invalid repetition: %d", meta.Repetition)
 	}
-	if time.Now().Before(meta.NextTime) {
+	if !meta.NextTime.Equal(clock.Now()) {
 		t.Errorf("Invalid next time: %v", meta.NextTime)
 	}
-	errors := []Score{
-		0, 1, 2,
-	}
-	for _, score := range errors {
-		meta.Review(score)
-		if meta.Repetition != 0 {
-			t.Errorf("%d: Invalid repetition: %d", score, meta.Repetition)
+	review := func(score Score, repetition int32, next time.Time, easiness float32) {
+		t.Helper()
+		clock.Add(time.Hour)
+		meta.Review(score, clock.Now())
+		if meta.Repetition != repetition {
+			t.Errorf("%d: invalid repetition: %d", score, meta.Repetition)
 		}
-		if time.Now().Before(meta.NextTime) {
-			t.Errorf("%d: Invalid next time: %v", score, meta.NextTime)
+		if !meta.NextTime.Equal(next) {
+			t.Errorf("%d: invalid next time: %v instead of %v", score,
+				meta.NextTime, next)
+		}
+		if !meta.LastTime.Equal(clock.Now()) {
+			t.Errorf("%d: invalid last time: %v", score, meta.LastTime)
+		}
+		if math.Abs(float64(meta.Easiness-easiness)) > 1e-5 {
+			t.Errorf("%d: invalid easiness: %f", score, meta.Easiness)
 		}
 	}
-	easiness := meta.Easiness
-	meta.Review(3)
-	if meta.Repetition != 1 {
-		t.Errorf("Invalid repetition: %d", meta.Repetition)
-	}
-	if time.Now().AddDate(0, 0, FirstRepetitionDelay).Before(meta.NextTime) {
-		t.Errorf("Invalid next time: %v", meta.NextTime)
-	}
-	if meta.Easiness > easiness {
-		t.Errorf("Easiness should not raise: %f", meta.Easiness)
-	}
-	easiness = meta.Easiness
-	meta.Review(4)
-	if meta.Repetition != 2 {
-		t.Errorf("Invalid repetition: %d", meta.Repetition)
-	}
-	if time.Now().AddDate(0, 0, SecondRepetitionDelay).Before(meta.NextTime) {
-		t.Errorf("Invalid next time: %v", meta.NextTime)
-	}
-	if meta.Easiness != easiness {
-		t.Errorf("Easiness not the same: %f", meta.Easiness)
-	}
-	easiness = meta.Easiness
-	meta.Review(5)
-	if meta.Repetition != 3 {
-		t.Errorf("Invalid repetition: %d", meta.Repetition)
-	}
-	days := SecondRepetitionDelay*2*float64(easiness) - 1
-	if time.Now().AddDate(0, 0, int(days)).After(meta.NextTime) {
-		t.Errorf("Invalid next time: %v", meta.NextTime)
-	}
-	days = SecondRepetitionDelay*2*float64(easiness) + 1
-	if time.Now().AddDate(0, 0, int(days)).Before(meta.NextTime) {
-		t.Errorf("Invalid next time: %v", meta.NextTime)
-	}
-	if meta.Easiness <= easiness {
-		t.Errorf("Easiness should raise: %f", meta.Easiness)
-	}
-	meta.Review(2)
-	if meta.Repetition != 0 {
-		t.Errorf("Invalid repetition: %d", meta.Repetition)
-	}
-	if time.Now().Before(meta.NextTime) {
-		t.Errorf("Invalid next time: %v", meta.NextTime)
+	for _, score := range []Score{0, 1, 2} {
+		review(score, 0, clock.Now().Add(time.Hour), 2.5)
 	}
+	review(3, 1, time.Date(2024, 1, 7, 14, 0, 0, 0, time.UTC), 2.36)
+	review(4, 2, time.Date(2024, 2, 6, 15, 0, 0, 0, time.UTC), 2.36)
+	// 2 repetitions of 36 days at an easiness of 2.36: 169 days.
+	review(5, 3, time.Date(2024, 6, 18, 16, 0, 0, 0, time.UTC), 2.46)
+	review(2, 0, time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC), 2.46)
 	for i := 0; i < 100; i++ {
-		meta.Review(3)
+		meta.Review(3, clock.Now())
 	}
-	if meta.Easiness < 1.3 {
+	if meta.Easiness != minimumEasiness {
 		t.Errorf("Easiness should plateau: %f", meta.Easiness)
 	}
 }
 
 func TestWriteRead(t *testing.T) {
 	var buf bytes.Buffer
-	err := writeDB(&buf, metaInput)
+	err := writeDB(&buf, metaDB{Cards: metaInput})
 	if err != nil {
 		t.Fatal(err)
 	}
-	output, err := readDB(&buf)
+	db, err := readDB(&buf)
 	if err != nil {
 		t.Fatal(err)
 	}
+	output := db.Cards
 	if len(metaInput) != len(output) {
 		t.Errorf("len %d, expected: %d", len(output), len(metaInput))
 	}
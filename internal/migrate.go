@@ -0,0 +1,86 @@
+package flashdown
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MetaVersion is the version of the meta data format written by this
+// program.
+const MetaVersion = 2
+
+// ErrMetaVersion is returned when the meta data were written by a newer
+// version of the program: they are not read to avoid losing information.
+var ErrMetaVersion = errors.New("Unsupported progress file version")
+
+// metaDB is the content of the meta data file.
+type metaDB struct {
+	Version   int
+	Scheduler string    // name of the scheduler used for the last save
+	Checksum  string    // checksum of the deck at the last save
+	Created   time.Time `json:",omitempty"`
+	Updated   time.Time `json:",omitempty"`
+	Cards     []Meta
+}
+
+// migration converts the meta data file of one version into the next
+// version.
+type migration func(data []byte) ([]byte, error)
+
+// migrations[i] upgrades the version i to the version i+1.
+var migrations = map[int]migration{
+	1: migrateV1,
+}
+
+// migrateV1 wraps the list of meta data of the version 1 into an envelope.
+func migrateV1(data []byte) ([]byte, error) {
+	var metas []Meta
+	if err := json.Unmarshal(data, &metas); err != nil {
+		return nil, err
+	}
+	return json.Marshal(metaDB{Version: 2, Cards: metas})
+}
+
+// metaVersion returns the version of a meta data file. The version 1 is a
+// JSON array without version.
+func metaVersion(data []byte) (int, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return 1, nil
+	}
+	var header struct{ Version int }
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	if header.Version < 1 {
+		return 0, fmt.Errorf("Invalid progress file version: %d", header.Version)
+	}
+	return header.Version, nil
+}
+
+// migrate upgrades a meta data file to MetaVersion. Newer versions are
+// refused.
+func migrate(data []byte) ([]byte, error) {
+	version, err := metaVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	if version > MetaVersion {
+		return nil, fmt.Errorf("%w: %d (newer than %d)", ErrMetaVersion,
+			version, MetaVersion)
+	}
+	for ; version < MetaVersion; version++ {
+		upgrade, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("%w: no migration from %d", ErrMetaVersion,
+				version)
+		}
+		if data, err = upgrade(data); err != nil {
+			return nil, fmt.Errorf("Failed to migrate from version %d: %w",
+				version, err)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,98 @@
+package flashdown
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// readDBFile reads a meta data file from the test data.
+func readDBFile(t *testing.T, filename string) (metaDB, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	return readDB(file)
+}
+
+func TestMigrateV1(t *testing.T) {
+	db, err := readDBFile(t, "samples/testdata/meta-v1.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Version != MetaVersion || len(db.Cards) != 2 {
+		t.Fatalf("Invalid migration: version %d, %d cards", db.Version, len(db.Cards))
+	}
+	if db.Cards[0].Repetition != 1 || db.Cards[1].Stability != 3.2 {
+		t.Errorf("Meta data lost: %v", db.Cards)
+	}
+	db.Scheduler = "sm2"
+	db.Checksum = "sha256:0000"
+	db.Created = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	db.Updated = time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	if err := writeDB(&buf, db); err != nil {
+		t.Fatal(err)
+	}
+	golden := "samples/testdata/meta-v2.db"
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Output differs from %s:\n%s", golden, buf.String())
+	}
+
+	// The current version is read without change.
+	db, err = readDBFile(t, golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := writeDB(&buf, db); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("Round trip differs from %s:\n%s", golden, buf.String())
+	}
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	_, err := readDBFile(t, "samples/testdata/meta-v99.db")
+	if !errors.Is(err, ErrMetaVersion) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := migrate([]byte(`{"Version": 0}`)); err == nil {
+		t.Errorf("Missing error")
+	}
+}
+
+func TestValidMeta(t *testing.T) {
+	current, err := os.ReadFile("samples/testdata/meta-v2.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := os.ReadFile("samples/testdata/meta-v99.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ValidMeta(current) {
+		t.Errorf("Valid meta data rejected")
+	}
+	for _, data := range [][]byte{newer, current[:len(current)/2], []byte(`{"Version": 2, "Cards": 1}`), nil} {
+		if ValidMeta(data) {
+			t.Errorf("Invalid meta data accepted: %.40q", data)
+		}
+	}
+}
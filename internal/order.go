@@ -0,0 +1,83 @@
+package flashdown
+
+import (
+	"sort"
+	"time"
+)
+
+// Orders of the cards of a session, see OrderCards.
+const (
+	OrderRandom         = "random"         // shuffled
+	OrderOverdue        = "overdue"        // most overdue first
+	OrderRetrievability = "retrievability" // most likely forgotten first
+	OrderRoundRobin     = "round-robin"    // one card of each deck in turn
+)
+
+// Orders lists the orders supported by OrderCards.
+var Orders = []string{OrderRandom, OrderOverdue, OrderRetrievability, OrderRoundRobin}
+
+// OrderCards sorts the cards of a session. The new cards come after the
+// reviews in a random order, except with OrderRoundRobin and OrderRandom
+// which mix them. An empty or unknown order is OrderRandom.
+func OrderCards(cards []Card, order string, now time.Time) []Card {
+	cards = ShuffleCards(cards)
+	switch order {
+	case OrderOverdue:
+		sort.SliceStable(cards, func(i, j int) bool {
+			a, b := cards[i].Meta, cards[j].Meta
+			if a.State() != b.State() {
+				return a.State() == StateReview
+			}
+			return a.State() == StateReview && a.NextTime.Before(b.NextTime)
+		})
+	case OrderRetrievability:
+		sort.SliceStable(cards, func(i, j int) bool {
+			a, b := cards[i].Meta, cards[j].Meta
+			if a.State() != b.State() {
+				return a.State() == StateReview
+			}
+			return a.State() == StateReview &&
+				retrievability(a, now) < retrievability(b, now)
+		})
+	case OrderRoundRobin:
+		cards = roundRobin(cards)
+	}
+	return cards
+}
+
+// retrievability returns the probability to recall a card. For the cards
+// scheduled with SM-2, the stability is estimated by their interval.
+func retrievability(m *Meta, now time.Time) float64 {
+	if m.Stability > 0 {
+		return m.Retrievability(now)
+	}
+	interval := m.Interval()
+	if interval <= 0 {
+		return 0
+	}
+	elapsed := interval + daysBetween(m.NextTime, now)
+	return forgettingCurve(elapsed, interval)
+}
+
+// roundRobin interleaves the cards of the decks, keeping the order of the
+// cards of each deck.
+func roundRobin(cards []Card) []Card {
+	decks := make([]*Deck, 0)
+	queues := make(map[*Deck][]Card)
+	for _, card := range cards {
+		if _, ok := queues[card.deck]; !ok {
+			decks = append(decks, card.deck)
+		}
+		queues[card.deck] = append(queues[card.deck], card)
+	}
+	sorted := make([]Card, 0, len(cards))
+	for len(sorted) < len(cards) {
+		for _, deck := range decks {
+			if queue := queues[deck]; len(queue) != 0 {
+				sorted = append(sorted, queue[0])
+				queues[deck] = queue[1:]
+			}
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,48 @@
+package flashdown
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderCards(t *testing.T) {
+	now := time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)
+	days := func(n int) time.Time { return now.AddDate(0, 0, n) }
+	a, b := &Deck{Name: "a"}, &Deck{Name: "b"}
+	newCards := func() []Card {
+		return []Card{
+			// Due 1 day ago after an interval of 2 days.
+			{Question: "a1", deck: a, Meta: &Meta{Repetition: 1,
+				LastTime: days(-3), NextTime: days(-1)}},
+			// Due 2 days ago after an interval of 30 days.
+			{Question: "a2", deck: a, Meta: &Meta{Repetition: 3,
+				LastTime: days(-32), NextTime: days(-2)}},
+			{Question: "a3", deck: a, Meta: &Meta{NextTime: days(-5)}},
+			{Question: "b1", deck: b, Meta: &Meta{Repetition: 1,
+				LastTime: days(-10), NextTime: days(0)}},
+		}
+	}
+	questions := func(cards []Card) string {
+		s := ""
+		for _, card := range cards {
+			s += card.Question + " "
+		}
+		return s
+	}
+
+	if s := questions(OrderCards(newCards(), OrderOverdue, now)); s != "a2 a1 b1 a3 " {
+		t.Errorf("Invalid overdue order: %s", s)
+	}
+	if s := questions(OrderCards(newCards(), OrderRetrievability, now)); s != "a1 a2 b1 a3 " {
+		t.Errorf("Invalid retrievability order: %s", s)
+	}
+	for i := 0; i < 10; i++ {
+		cards := OrderCards(newCards(), OrderRoundRobin, now)
+		if cards[0].deck == cards[1].deck || len(cards) != 4 {
+			t.Errorf("Invalid round-robin order: %s", questions(cards))
+		}
+	}
+	if cards := OrderCards(newCards(), OrderRandom, now); len(cards) != 4 {
+		t.Errorf("Invalid random order: %s", questions(cards))
+	}
+}
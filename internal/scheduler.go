@@ -0,0 +1,94 @@
+package flashdown
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scheduler decides when a card should be asked again.
+type Scheduler interface {
+	// Name identifies the algorithm (ex: "sm2").
+	Name() string
+	// Schedule returns the new state of a card given its current state,
+	// the score of the answer and the time of the review. The due date
+	// of the card is the NextTime of the returned Meta.
+	Schedule(m Meta, s Score, now time.Time) Meta
+}
+
+// DefaultScheduler is the scheduler used by new decks.
+var DefaultScheduler Scheduler = NewSM2Scheduler()
+
+// NewScheduler returns the scheduler with default parameters identified by
+// name.
+func NewScheduler(name string) (Scheduler, error) {
+	switch strings.ToLower(name) {
+	case "sm2":
+		return NewSM2Scheduler(), nil
+	case "fsrs":
+		return NewFSRSScheduler(), nil
+	}
+	return nil, fmt.Errorf("Unknown scheduler: %s", name)
+}
+
+// SM2Scheduler implements a modified version of SM-2.
+// See https://en.wikipedia.org/wiki/SuperMemo
+type SM2Scheduler struct {
+	FirstRepetitionDelay  int // in days.
+	SecondRepetitionDelay int // in days.
+}
+
+// NewSM2Scheduler returns a SM-2 scheduler. FirstRepetitionDelay and
+// SecondRepetitionDelay have been modified, originally they were 1 and 6.
+func NewSM2Scheduler() *SM2Scheduler {
+	return &SM2Scheduler{
+		FirstRepetitionDelay:  FirstRepetitionDelay,
+		SecondRepetitionDelay: SecondRepetitionDelay,
+	}
+}
+
+func (a *SM2Scheduler) Name() string {
+	return "sm2"
+}
+
+func (a *SM2Scheduler) Schedule(c Meta, s Score, now time.Time) Meta {
+	c.LastTime = now
+	if s >= 3 {
+		switch c.Repetition {
+		case 0:
+			c.NextTime = now.AddDate(0, 0, a.FirstRepetitionDelay)
+		case 1:
+			c.NextTime = now.AddDate(0, 0, a.SecondRepetitionDelay)
+		default:
+			// 6 days per successful repetition
+			sinceLastTime := float64(c.Repetition) * float64(a.SecondRepetitionDelay)
+			days := int(sinceLastTime * float64(c.Easiness))
+			c.NextTime = now.AddDate(0, 0, days)
+		}
+
+		Q := 5.0 - float32(s)
+		c.Easiness = c.Easiness + 0.1 - Q*0.08 - Q*Q*0.02
+		if c.Easiness < minimumEasiness {
+			c.Easiness = minimumEasiness
+		}
+		c.Repetition++
+	} else {
+		c.Repetition = 0
+		c.NextTime = now
+	}
+	return c
+}
+
+// interval returns the interval in days computed when a card reaches a
+// given number of repetitions.
+func (a *SM2Scheduler) interval(repetition int32, easiness float32) int {
+	switch repetition {
+	case 1:
+		return a.FirstRepetitionDelay
+	case 2:
+		return a.SecondRepetitionDelay
+	default:
+		sinceLastTime := float64(repetition-1) * float64(a.SecondRepetitionDelay)
+		return int(sinceLastTime * float64(easiness))
+	}
+}
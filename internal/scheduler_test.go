@@ -0,0 +1,51 @@
+package flashdown
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewScheduler(t *testing.T) {
+	for _, name := range []string{"sm2", "fsrs"} {
+		s, err := NewScheduler(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Name() != name {
+			t.Errorf("%s instead of %s", s.Name(), name)
+		}
+	}
+	if _, err := NewScheduler("unknown"); err == nil {
+		t.Error("missing error")
+	}
+}
+
+func TestSM2Schedule(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	scheduler := NewSM2Scheduler()
+	meta := Meta{Easiness: defaultEasiness, NextTime: now}
+
+	meta = scheduler.Schedule(meta, CorrectEasy, now)
+	if !meta.NextTime.Equal(now.AddDate(0, 0, FirstRepetitionDelay)) {
+		t.Errorf("Invalid next time: %v", meta.NextTime)
+	}
+	now = meta.NextTime
+	meta = scheduler.Schedule(meta, CorrectEasy, now)
+	if !meta.NextTime.Equal(now.AddDate(0, 0, SecondRepetitionDelay)) {
+		t.Errorf("Invalid next time: %v", meta.NextTime)
+	}
+	now = meta.NextTime
+	meta = scheduler.Schedule(meta, CorrectEasy, now)
+	days := int(2 * SecondRepetitionDelay * defaultEasiness)
+	if !meta.NextTime.Equal(now.AddDate(0, 0, days)) {
+		t.Errorf("Invalid next time: %v", meta.NextTime)
+	}
+	if !meta.LastTime.Equal(now) {
+		t.Errorf("Invalid last time: %v", meta.LastTime)
+	}
+	now = now.Add(time.Hour)
+	meta = scheduler.Schedule(meta, IncorrectEasy, now)
+	if meta.Repetition != 0 || !meta.NextTime.Equal(now) {
+		t.Errorf("Invalid reset: %d, %v", meta.Repetition, meta.NextTime)
+	}
+}
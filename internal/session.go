@@ -0,0 +1,200 @@
+package flashdown
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// ErrSessionChanged is returned by ResumeGame when the session can't be
+// resumed because its decks are missing or its cards were removed.
+var ErrSessionChanged = errors.New("The decks of the session changed")
+
+// Session is the state of a Game saved to resume it later.
+type Session struct {
+	Decks          []SessionDeck
+	Cards          []SessionCard // cards of the session in order
+	Index          int           // position of the current card
+	Success        int
+	Total          int
+	Learning       bool            `json:",omitempty"`
+	LearningGap    int             `json:",omitempty"`
+	LearningSteps  []time.Duration `json:",omitempty"`
+	SuspendLeeches bool            `json:",omitempty"`
+	Fuzz           bool            `json:",omitempty"`
+	LoadBalance    bool            `json:",omitempty"`
+	Saved          time.Time
+}
+
+// SessionDeck identifies a deck of a session.
+type SessionDeck struct {
+	Name     string
+	Checksum string
+}
+
+// SessionCard identifies a card of a session.
+type SessionCard struct {
+	Deck  int // index in Session.Decks
+	Hash  Digest
+	Due   time.Time `json:",omitempty"` // see GameOptions.LearningSteps
+	Fails int       `json:",omitempty"` // failures during the session
+}
+
+// Remaining returns the number of cards left to answer.
+func (s *Session) Remaining() int {
+	return len(s.Cards) - s.Index
+}
+
+// Session returns the state of the game. The undo history isn't saved.
+func (g *Game) Session() *Session {
+	s := &Session{
+		Decks:          make([]SessionDeck, len(g.decks)),
+		Cards:          make([]SessionCard, 0, len(g.cards)),
+		Index:          g.index,
+		Success:        g.success,
+		Total:          g.total,
+		Learning:       g.learning.enabled,
+		LearningGap:    g.learning.gap,
+		LearningSteps:  g.learning.steps,
+		SuspendLeeches: g.suspendLeeches,
+		Fuzz:           g.fuzz.enabled,
+		LoadBalance:    g.fuzz.balance,
+		Saved:          g.clock.Now(),
+	}
+	decks := make(map[*Deck]int, len(g.decks))
+	for i, deck := range g.decks {
+		decks[deck] = i
+		s.Decks[i] = SessionDeck{Name: deck.Name, Checksum: deck.Checksum}
+	}
+	for i, card := range g.cards {
+		deck, ok := decks[card.deck]
+		if !ok {
+			continue
+		}
+		s.Cards = append(s.Cards, SessionCard{
+			Deck:  deck,
+			Hash:  card.Meta.Hash,
+			Due:   g.due[i],
+			Fails: g.learning.fails[card.Meta],
+		})
+	}
+	if g.finished {
+		s.Index = len(s.Cards)
+	}
+	return s
+}
+
+// sessionDeck returns the deck of a session among decks with the same
+// name: first the deck with the same checksum, preferably at the same
+// position, then the deck at the same position, then the only deck with
+// this name. It returns nil if none matches.
+func sessionDeck(sd SessionDeck, index int, decks []*Deck) *Deck {
+	same := func(i int) bool { return i < len(decks) && decks[i].Name == sd.Name }
+	if same(index) && decks[index].Checksum == sd.Checksum {
+		return decks[index]
+	}
+	var found *Deck
+	matches := 0
+	for i, deck := range decks {
+		if !same(i) {
+			continue
+		}
+		if deck.Checksum == sd.Checksum {
+			return deck
+		}
+		found = deck
+		matches++
+	}
+	if same(index) {
+		return decks[index]
+	}
+	if matches != 1 {
+		return nil
+	}
+	return found
+}
+
+// ResumeGame restores a session with its decks, given in the same order as
+// when the session was saved or matched by name (see sessionDeck). The
+// cards of the decks edited since the session was saved are found by
+// digest and the cards which don't exist anymore are skipped.
+// ErrSessionChanged is returned if a deck is missing or if no card is left
+// to answer. The game uses the clock of the first deck.
+func ResumeGame(s *Session, decks ...*Deck) (*Game, error) {
+	game := &Game{
+		cards: make([]Card, 0, len(s.Cards)),
+		due:   make([]time.Time, 0, len(s.Cards)),
+		decks: make([]*Deck, len(s.Decks)),
+		clock: DefaultClock,
+		learning: learning{
+			enabled: s.Learning,
+			gap:     s.LearningGap,
+			steps:   s.LearningSteps,
+			fails:   make(map[*Meta]int),
+		},
+		success:        s.Success,
+		total:          s.Total,
+		suspendLeeches: s.SuspendLeeches,
+		fuzz:           fuzz{enabled: s.Fuzz, balance: s.LoadBalance},
+	}
+	if game.learning.gap <= 0 {
+		game.learning.gap = DefaultLearningGap
+	}
+	cards := make([]map[Digest]Card, len(s.Decks))
+	for i, sd := range s.Decks {
+		game.decks[i] = sessionDeck(sd, i, decks)
+		if game.decks[i] == nil {
+			return nil, fmt.Errorf("%w: %s is missing", ErrSessionChanged, sd.Name)
+		}
+		if game.decks[i].Checksum != sd.Checksum {
+			log.Printf("%s changed since the session was saved", sd.Name)
+		}
+		cards[i] = make(map[Digest]Card, len(game.decks[i].Cards))
+		for _, card := range game.decks[i].Cards {
+			cards[i][card.Meta.Hash] = card
+		}
+	}
+	for i, sc := range s.Cards {
+		if sc.Deck < 0 || sc.Deck >= len(cards) {
+			return nil, fmt.Errorf("%w: invalid deck %d", ErrSessionChanged, sc.Deck)
+		}
+		card, ok := cards[sc.Deck][sc.Hash]
+		if !ok {
+			if i < s.Index {
+				game.index--
+			}
+			continue
+		}
+		game.cards = append(game.cards, card)
+		game.due = append(game.due, sc.Due)
+		if sc.Fails != 0 {
+			game.learning.fails[card.Meta] = sc.Fails
+		}
+	}
+	game.index += s.Index
+	if game.index < 0 || game.index >= len(game.cards) {
+		return nil, fmt.Errorf("%w: no card left", ErrSessionChanged)
+	}
+	if len(game.decks) != 0 && game.decks[0].Clock != nil {
+		game.clock = game.decks[0].Clock
+	}
+	game.asked = game.clock.Now()
+	return game, nil
+}
+
+// ReadSession parses a session saved by WriteSession.
+func ReadSession(r io.Reader) (*Session, error) {
+	var s Session
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// WriteSession saves a session as JSON.
+func WriteSession(w io.Writer, s *Session) error {
+	return json.NewEncoder(w).Encode(s)
+}
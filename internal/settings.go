@@ -0,0 +1,133 @@
+package flashdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DeckSettings are the settings of a deck. They are defined by an optional
+// YAML front matter at the beginning of the deck:
+//
+//	---
+//	title: Latency numbers
+//	new_cards_per_day: 10
+//	reviews_per_day: 100
+//	leech_threshold: 8
+//	suspend_leeches: true
+//	scheduler:
+//	  name: fsrs
+//	---
+type DeckSettings struct {
+	Title          string            `yaml:"title"`
+	Description    string            `yaml:"description"`
+	Tags           []string          `yaml:"tags"`              // added to every card
+	NewCardsPerDay int               `yaml:"new_cards_per_day"` // 0 means no limit
+	ReviewsPerDay  int               `yaml:"reviews_per_day"`   // 0 means no limit
+	LeechThreshold int               `yaml:"leech_threshold"`   // 0 means DefaultLeechThreshold
+	SuspendLeeches bool              `yaml:"suspend_leeches"`   // leeches are not asked anymore
+	Reverse        bool              `yaml:"reverse"`           // all cards are two-sided
+	Scheduler      SchedulerSettings `yaml:"scheduler"`
+}
+
+// SchedulerSettings selects the scheduler of a deck and its parameters.
+// Zero values keep the default parameters.
+type SchedulerSettings struct {
+	Name                  string    `yaml:"name"` // sm2 or fsrs
+	FirstRepetitionDelay  int       `yaml:"first_repetition_delay"`
+	SecondRepetitionDelay int       `yaml:"second_repetition_delay"`
+	RequestRetention      float64   `yaml:"request_retention"`
+	MaximumInterval       int       `yaml:"maximum_interval"`
+	Weights               []float64 `yaml:"weights"`
+}
+
+func (s SchedulerSettings) isZero() bool {
+	return s.Name == "" && s.FirstRepetitionDelay == 0 &&
+		s.SecondRepetitionDelay == 0 && s.RequestRetention == 0 &&
+		s.MaximumInterval == 0 && len(s.Weights) == 0
+}
+
+// newScheduler returns the scheduler described by the settings. When no
+// name is given, the parameters apply to the fallback algorithm.
+func (s SchedulerSettings) newScheduler(fallback Scheduler) (Scheduler, error) {
+	if s.isZero() {
+		return fallback, nil
+	}
+	name := s.Name
+	if name == "" {
+		name = fallback.Name()
+	}
+	switch strings.ToLower(name) {
+	case "sm2":
+		sm2 := NewSM2Scheduler()
+		if s.FirstRepetitionDelay > 0 {
+			sm2.FirstRepetitionDelay = s.FirstRepetitionDelay
+		}
+		if s.SecondRepetitionDelay > 0 {
+			sm2.SecondRepetitionDelay = s.SecondRepetitionDelay
+		}
+		return sm2, nil
+	case "fsrs":
+		fsrs := NewFSRSScheduler()
+		if s.RequestRetention > 0 && s.RequestRetention < 1 {
+			fsrs.Parameters.RequestRetention = s.RequestRetention
+		}
+		if s.MaximumInterval > 0 {
+			fsrs.Parameters.MaximumInterval = s.MaximumInterval
+		}
+		if len(s.Weights) != 0 {
+			if len(s.Weights) != len(fsrs.Parameters.Weights) {
+				return nil, fmt.Errorf("FSRS expects %d weights, got %d",
+					len(fsrs.Parameters.Weights), len(s.Weights))
+			}
+			copy(fsrs.Parameters.Weights[:], s.Weights)
+		}
+		return fsrs, nil
+	}
+	return nil, fmt.Errorf("Unknown scheduler: %s", name)
+}
+
+// isFrontMatterDelimiter returns true for the lines which start or end a
+// front matter.
+func isFrontMatterDelimiter(line string) bool {
+	line = strings.TrimRight(line, " \t\r")
+	return line == "---" || line == "..."
+}
+
+// parseFrontMatter extracts the settings from the front matter of a deck. It
+// returns the deck with the front matter replaced by empty lines so that the
+// line numbers of the cards are preserved. A first line "---" starts a front
+// matter only if it is closed and contains a YAML mapping (or nothing):
+// otherwise it is part of the deck. Unknown settings are an error.
+func parseFrontMatter(md string) (DeckSettings, string, error) {
+	var settings DeckSettings
+	lines := strings.Split(md, "\n")
+	if len(lines) == 0 || strings.TrimRight(lines[0], " \t\r") != "---" {
+		return settings, md, nil
+	}
+	for i := 1; i < len(lines); i++ {
+		if !isFrontMatterDelimiter(lines[i]) {
+			continue
+		}
+		yamlText := []byte(strings.Join(lines[1:i], "\n"))
+		var node yaml.Node
+		if err := yaml.Unmarshal(yamlText, &node); err != nil {
+			return settings, md, nil
+		}
+		if node.Kind != 0 && (len(node.Content) == 0 ||
+			node.Content[0].Kind != yaml.MappingNode) {
+			return settings, md, nil
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(yamlText))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&settings); err != nil && err != io.EOF {
+			return settings, md, fmt.Errorf("Invalid front matter: %w", err)
+		}
+		body := strings.Repeat("\n", i+1) + strings.Join(lines[i+1:], "\n")
+		return settings, body, nil
+	}
+	return settings, md, nil
+}
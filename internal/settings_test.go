@@ -0,0 +1,121 @@
+package flashdown
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFrontMatter(t *testing.T) {
+	input := `---
+title: Vocabulary
+description: French words
+tags: [french, language]
+new_cards_per_day: 1
+reverse: true
+scheduler:
+  name: fsrs
+  request_retention: 0.85
+---
+## chat
+cat
+
+## chien
+dog
+`
+	cards, settings, err := readDeck(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Title != "Vocabulary" || settings.Description != "French words" ||
+		len(settings.Tags) != 2 || settings.NewCardsPerDay != 1 ||
+		!settings.Reverse {
+		t.Errorf("Invalid settings: %+v", settings)
+	}
+	if len(cards) != 4 || !cards[1].Reversed {
+		t.Fatalf("Reverse mode not applied: %d", len(cards))
+	}
+	if cards[0].Line != 11 || cards[2].Line != 14 {
+		t.Errorf("Invalid lines: %d, %d", cards[0].Line, cards[2].Line)
+	}
+	scheduler, err := settings.Scheduler.newScheduler(NewSM2Scheduler())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsrs, ok := scheduler.(*FSRSScheduler)
+	if !ok || fsrs.Parameters.RequestRetention != 0.85 {
+		t.Errorf("Invalid scheduler: %#v", scheduler)
+	}
+}
+
+func TestInvalidFrontMatter(t *testing.T) {
+	inputs := []string{
+		"---\ntitle: unterminated\n## question\nanswer\n",
+		"---\ntitle: [invalid\n---\n## question\nanswer\n",
+		"---\nscheduler:\n  name: unknown\n---\n## question\nanswer\n",
+		"---\nnew_card_per_day: 10\n---\n## question\nanswer\n",
+	}
+	for i, input := range inputs {
+		accessor := &memoryAccessor{name: "memory.md", cards: input}
+		if _, err := NewDeck(accessor); err == nil {
+			t.Errorf("%d: missing error", i)
+		}
+	}
+}
+
+func TestHorizontalRule(t *testing.T) {
+	inputs := []string{
+		"---\n## question\nanswer\n---\n## question 2\nanswer\n",
+		"---\n---\n## question\nanswer\n\n## question 2\nanswer\n",
+	}
+	for i, input := range inputs {
+		cards, settings, err := readDeck(bytes.NewBufferString(input))
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if len(cards) != 2 || settings.Title != "" {
+			t.Errorf("%d: invalid cards: %v", i, cards)
+		}
+	}
+}
+
+func TestNewCardsPerDay(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `---
+title: Limited
+new_cards_per_day: 2
+---
+## question 1
+answer 1
+## question 2
+answer 2
+## question 3
+answer 3
+`)
+	if deck.Title() != "Limited" {
+		t.Errorf("Invalid title: %s", deck.Title())
+	}
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	if _, total := game.Progress(); total != 2 {
+		t.Errorf("Invalid number of cards: %d", total)
+	}
+	if game.DeckName() != "Limited" {
+		t.Errorf("Invalid deck name: %s", game.DeckName())
+	}
+
+	// The limit is per day, not per session.
+	for !game.IsFinished() {
+		game.Review(PerfectRecall)
+	}
+	clock.Add(time.Hour)
+	game = NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	if !game.IsFinished() {
+		t.Errorf("New cards studied twice the same day")
+	}
+	clock.Add(24 * time.Hour)
+	game = NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	if _, total := game.Progress(); total != 1 {
+		t.Errorf("Invalid number of cards the next day: %d", total)
+	}
+}
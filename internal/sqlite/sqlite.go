@@ -0,0 +1,573 @@
+// Package sqlite reads the tables of SQLite database files without cgo. It
+// only supports what is needed to exchange Anki collections: full scans of
+// the rowid tables of UTF-8 databases. A complete pure Go driver like
+// modernc.org/sqlite is a large dependency for these few reads.
+//
+// The databases come from untrusted files: a malformed database returns
+// ErrFormat, it never panics and the rows read from a table are never larger
+// than the file.
+//
+// See https://www.sqlite.org/fileformat.html
+package sqlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	headerSize  = 100
+	magic       = "SQLite format 3\x00"
+	maxDepth    = 64 // maximum depth of the b-trees
+	schemaTable = "sqlite_schema"
+)
+
+// Page types.
+const (
+	interiorTable = 0x05
+	leafTable     = 0x0d
+)
+
+var (
+	// ErrFormat is returned when the file isn't a supported database.
+	ErrFormat = errors.New("sqlite: invalid database")
+	// ErrNoTable is returned when a table doesn't exist.
+	ErrNoTable = errors.New("sqlite: no such table")
+)
+
+// Row is a row of a table indexed by column name. Values are nil, int64,
+// float64, string or []byte.
+type Row map[string]interface{}
+
+// Int returns the value of an integer column, 0 if it isn't an integer.
+func (r Row) Int(column string) int64 {
+	switch v := r[column].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+// Float returns the value of a numeric column, 0 if it isn't a number.
+func (r Row) Float(column string) float64 {
+	switch v := r[column].(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// String returns the value of a text column, "" if it isn't a text or a
+// blob.
+func (r Row) String(column string) string {
+	switch v := r[column].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// Bytes returns the value of a blob column, nil if it isn't a blob or a
+// text.
+func (r Row) Bytes(column string) []byte {
+	switch v := r[column].(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+// table describes a table of the schema.
+type table struct {
+	root    int      // root page of the b-tree
+	columns []string // column names in order
+	rowid   int      // index of the INTEGER PRIMARY KEY column, -1 if none
+}
+
+// DB is a read only database loaded in memory.
+type DB struct {
+	data     []byte
+	pageSize int
+	usable   int // page size without the reserved bytes
+	tables   map[string]table
+}
+
+// Open reads a database file.
+func Open(filename string) (*DB, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return New(data)
+}
+
+// New reads a database from its content.
+func New(data []byte) (*DB, error) {
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return nil, ErrFormat
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("%w: page size %d", ErrFormat, pageSize)
+	}
+	if encoding := binary.BigEndian.Uint32(data[56:60]); encoding > 1 {
+		return nil, fmt.Errorf("%w: text encoding %d isn't UTF-8", ErrFormat, encoding)
+	}
+	db := &DB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+		tables: map[string]table{
+			schemaTable: {
+				root:    1,
+				columns: []string{"type", "name", "tbl_name", "rootpage", "sql"},
+				rowid:   -1,
+			},
+		},
+	}
+	schema, err := db.Rows(schemaTable)
+	if err != nil {
+		return nil, err
+	}
+	// The tables don't share pages: a root used twice is an error.
+	roots := map[int64]bool{1: true}
+	for _, row := range schema {
+		if row.String("type") != "table" {
+			continue
+		}
+		columns, rowid, err := parseColumns(row.String("sql"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrFormat, err)
+		}
+		root := row.Int("rootpage")
+		if roots[root] {
+			return nil, fmt.Errorf("%w: page %d is the root of several tables", ErrFormat, root)
+		}
+		roots[root] = true
+		db.tables[row.String("name")] = table{
+			root:    int(root),
+			columns: columns,
+			rowid:   rowid,
+		}
+	}
+	return db, nil
+}
+
+// Tables returns the names of the tables, sorted.
+func (db *DB) Tables() []string {
+	names := make([]string, 0, len(db.tables))
+	for name := range db.tables {
+		if name != schemaTable {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Columns returns the column names of a table.
+func (db *DB) Columns(name string) ([]string, error) {
+	t, ok := db.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoTable, name)
+	}
+	return t.columns, nil
+}
+
+// Rows returns all the rows of a table, sorted by rowid.
+func (db *DB) Rows(name string) ([]Row, error) {
+	t, ok := db.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoTable, name)
+	}
+	rows := make([]Row, 0)
+	// Each page is read once: a cycle in the b-tree or in the overflow
+	// pages is an error.
+	visited := make(map[int]bool)
+	// The cells of a table don't overlap: the rows can't be larger than
+	// the file. This bounds the work when many cells point to the same
+	// large record.
+	budget := len(db.data)
+	err := db.walk(t.root, 0, visited, func(rowid int64, payload []byte) error {
+		budget -= max(len(payload), 1)
+		if budget < 0 {
+			return fmt.Errorf("%w: rows larger than the file", ErrFormat)
+		}
+		values, err := decodeRecord(payload)
+		if err != nil {
+			return err
+		}
+		row := make(Row, len(t.columns))
+		for i, column := range t.columns {
+			if i < len(values) {
+				row[column] = values[i]
+			} else {
+				row[column] = nil // column added after the row
+			}
+		}
+		if t.rowid >= 0 {
+			row[t.columns[t.rowid]] = rowid
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return rows, nil
+}
+
+// pages returns the number of pages of the database.
+func (db *DB) pages() int {
+	return len(db.data) / db.pageSize
+}
+
+// page returns the content of a page, starting at 1. A page already
+// visited is an error.
+func (db *DB) page(number int, visited map[int]bool) ([]byte, error) {
+	if number < 1 || number > db.pages() {
+		return nil, fmt.Errorf("%w: page %d out of range", ErrFormat, number)
+	}
+	if visited[number] {
+		return nil, fmt.Errorf("%w: page %d used twice", ErrFormat, number)
+	}
+	visited[number] = true
+	start := (number - 1) * db.pageSize
+	return db.data[start : start+db.pageSize], nil
+}
+
+// walk calls fn for each row of the table b-tree starting at page root.
+func (db *DB) walk(root, depth int, visited map[int]bool,
+	fn func(rowid int64, payload []byte) error) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%w: b-tree too deep", ErrFormat)
+	}
+	page, err := db.page(root, visited)
+	if err != nil {
+		return err
+	}
+	header := 0
+	if root == 1 {
+		header = headerSize
+	}
+	if header+8 > len(page) {
+		return fmt.Errorf("%w: page %d truncated", ErrFormat, root)
+	}
+	kind := page[header]
+	cells := int(binary.BigEndian.Uint16(page[header+3:]))
+	pointers := header + 8
+	if kind == interiorTable {
+		pointers = header + 12
+	} else if kind != leafTable {
+		return fmt.Errorf("%w: page %d isn't a table page (%#x)", ErrFormat, root, kind)
+	}
+	if pointers+2*cells > len(page) {
+		return fmt.Errorf("%w: page %d truncated", ErrFormat, root)
+	}
+	for i := 0; i < cells; i++ {
+		offset := int(binary.BigEndian.Uint16(page[pointers+2*i:]))
+		if offset >= len(page) {
+			return fmt.Errorf("%w: cell out of page %d", ErrFormat, root)
+		}
+		cell := page[offset:]
+		if kind == interiorTable {
+			if len(cell) < 4 {
+				return fmt.Errorf("%w: cell out of page %d", ErrFormat, root)
+			}
+			child := int(binary.BigEndian.Uint32(cell))
+			if err := db.walk(child, depth+1, visited, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		size, n := varint(cell)
+		cell = cell[n:]
+		rowid, n := varint(cell)
+		cell = cell[n:]
+		// A payload can't be larger than the database.
+		if size > uint64(len(db.data)) || size > math.MaxInt32 {
+			return fmt.Errorf("%w: payload too large", ErrFormat)
+		}
+		payload, err := db.payload(cell, int(size), visited)
+		if err != nil {
+			return err
+		}
+		if err := fn(int64(rowid), payload); err != nil {
+			return err
+		}
+	}
+	if kind == interiorTable {
+		right := int(binary.BigEndian.Uint32(page[header+8:]))
+		return db.walk(right, depth+1, visited, fn)
+	}
+	return nil
+}
+
+// localSize returns the number of bytes of a payload stored in a leaf table
+// cell, the rest being stored in overflow pages.
+func localSize(size, usable int) int {
+	maxLocal := usable - 35
+	if size <= maxLocal {
+		return size
+	}
+	minLocal := (usable-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(usable-4)
+	if local > maxLocal {
+		return minLocal
+	}
+	return local
+}
+
+// payload returns the payload of a cell, following the overflow pages.
+// size must not exceed the size of the database.
+func (db *DB) payload(cell []byte, size int, visited map[int]bool) ([]byte, error) {
+	local := localSize(size, db.usable)
+	if local > len(cell) {
+		return nil, fmt.Errorf("%w: payload out of page", ErrFormat)
+	}
+	if local == size {
+		return cell[:size], nil
+	}
+	if local+4 > len(cell) {
+		return nil, fmt.Errorf("%w: payload out of page", ErrFormat)
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, cell[:local]...)
+	next := int(binary.BigEndian.Uint32(cell[local:]))
+	for len(payload) < size {
+		page, err := db.page(next, visited)
+		if err != nil {
+			return nil, err
+		}
+		chunk := page[4:db.usable]
+		if remaining := size - len(payload); len(chunk) > remaining {
+			chunk = chunk[:remaining]
+		}
+		payload = append(payload, chunk...)
+		next = int(binary.BigEndian.Uint32(page))
+	}
+	return payload, nil
+}
+
+// varint decodes a SQLite variable length integer and returns its size.
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
+
+// decodeRecord returns the values of a record.
+func decodeRecord(payload []byte) ([]interface{}, error) {
+	headerLen, n := varint(payload)
+	if headerLen > uint64(len(payload)) || n == 0 {
+		return nil, fmt.Errorf("%w: invalid record", ErrFormat)
+	}
+	types := make([]uint64, 0)
+	for offset := n; offset < int(headerLen); {
+		t, n := varint(payload[offset:])
+		if n == 0 {
+			return nil, fmt.Errorf("%w: invalid record", ErrFormat)
+		}
+		types = append(types, t)
+		offset += n
+	}
+	body := payload[headerLen:]
+	values := make([]interface{}, len(types))
+	for i, t := range types {
+		size := serialSize(t)
+		if size > uint64(len(body)) {
+			return nil, fmt.Errorf("%w: invalid record", ErrFormat)
+		}
+		data := body[:size]
+		body = body[size:]
+		switch {
+		case t == 0:
+			values[i] = nil
+		case t <= 6:
+			values[i] = bigEndianInt(data)
+		case t == 7:
+			values[i] = math.Float64frombits(binary.BigEndian.Uint64(data))
+		case t == 8:
+			values[i] = int64(0)
+		case t == 9:
+			values[i] = int64(1)
+		case t >= 12 && t%2 == 0:
+			values[i] = append([]byte{}, data...)
+		case t >= 13:
+			values[i] = string(data)
+		default:
+			return nil, fmt.Errorf("%w: serial type %d", ErrFormat, t)
+		}
+	}
+	return values, nil
+}
+
+// serialSize returns the size of a value of serial type t.
+func serialSize(t uint64) uint64 {
+	switch t {
+	case 0, 8, 9, 10, 11:
+		return 0
+	case 1, 2, 3, 4:
+		return t
+	case 5:
+		return 6
+	case 6, 7:
+		return 8
+	}
+	if t%2 == 0 {
+		return (t - 12) / 2
+	}
+	return (t - 13) / 2
+}
+
+// bigEndianInt decodes a signed big endian integer of 1 to 8 bytes.
+func bigEndianInt(data []byte) int64 {
+	var v int64
+	if len(data) != 0 && data[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, b := range data {
+		v = v<<8 | int64(b)
+	}
+	return v
+}
+
+// parseColumns returns the column names of a CREATE TABLE statement and the
+// index of the column which aliases the rowid, -1 if none.
+func parseColumns(sql string) ([]string, int, error) {
+	start, end := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if start == -1 || end < start {
+		return nil, -1, nil
+	}
+	columns := make([]string, 0)
+	types := make([]string, 0)
+	rowid := -1
+	primaryKey := ""
+	for _, def := range splitDefinitions(sql[start+1 : end]) {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		upper := strings.ToUpper(def)
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT", "UNIQUE", "CHECK", "FOREIGN":
+			continue
+		case "PRIMARY":
+			// table constraint: PRIMARY KEY (column)
+			open, close := strings.Index(def, "("), strings.Index(def, ")")
+			if open != -1 && close > open && !strings.Contains(def[open:close], ",") {
+				fields := strings.Fields(def[open+1 : close])
+				if len(fields) == 0 {
+					return nil, -1, fmt.Errorf("invalid constraint: %s", strings.TrimSpace(def))
+				}
+				primaryKey = unquote(fields[0])
+			}
+			continue
+		}
+		name, rest := identifier(def)
+		columns = append(columns, name)
+		kind := ""
+		if fields := strings.Fields(rest); len(fields) != 0 {
+			kind = strings.ToUpper(fields[0])
+		}
+		types = append(types, kind)
+		if kind == "INTEGER" && strings.Contains(upper, "PRIMARY KEY") &&
+			!strings.Contains(upper, "DESC") {
+			rowid = len(columns) - 1
+		}
+	}
+	for i, column := range columns {
+		if column == primaryKey && types[i] == "INTEGER" {
+			rowid = i
+		}
+	}
+	return columns, rowid, nil
+}
+
+// splitDefinitions splits the column definitions on the commas which
+// aren't inside parenthesis or quotes.
+func splitDefinitions(s string) []string {
+	defs := make([]string, 0)
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '[':
+			quote = ']'
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			defs = append(defs, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(defs, s[start:])
+}
+
+// identifier returns the identifier at the beginning of s, without quotes,
+// and the rest of s.
+func identifier(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ""
+	}
+	closing := map[byte]byte{'"': '"', '`': '`', '\'': '\'', '[': ']'}
+	if end, ok := closing[s[0]]; ok {
+		if i := strings.IndexByte(s[1:], end); i != -1 {
+			return s[1 : i+1], s[i+2:]
+		}
+	}
+	if i := strings.IndexAny(s, " \t\n\r("); i != -1 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// unquote removes the quotes around an identifier.
+func unquote(name string) string {
+	if len(name) >= 2 {
+		switch name[0] {
+		case '"', '`', '\'':
+			if name[len(name)-1] == name[0] {
+				return name[1 : len(name)-1]
+			}
+		case '[':
+			if name[len(name)-1] == ']' {
+				return name[1 : len(name)-1]
+			}
+		}
+	}
+	return name
+}
@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestReadTable(t *testing.T) {
+	db, err := Open("../samples/testdata/test.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tables := db.Tables(); !reflect.DeepEqual(tables, []string{"items", "quoted table"}) {
+		t.Errorf("Invalid tables: %v", tables)
+	}
+	columns, err := db.Columns("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(columns, []string{"id", "name", "value", "data", "count", "extra"}) {
+		t.Errorf("Invalid columns: %v", columns)
+	}
+	rows, err := db.Rows("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 201 {
+		t.Fatalf("Invalid number of rows: %d", len(rows))
+	}
+	for i, row := range rows[:200] {
+		n := int64(i + 1)
+		if row.Int("id") != 3*n {
+			t.Errorf("%d: invalid id: %d", i, row.Int("id"))
+		}
+		name := row.String("name")
+		if n%50 == 0 {
+			if len(name) != 1505+int(n) || !strings.HasPrefix(name, "long xxx") {
+				t.Errorf("%d: invalid long name: %d", i, len(name))
+			}
+		} else if name != "item "+strconv.FormatInt(n, 10) {
+			t.Errorf("%d: invalid name: %s", i, name)
+		}
+		if row.Float("value") != float64(n)/4 {
+			t.Errorf("%d: invalid value: %v", i, row["value"])
+		}
+		if b := row.Bytes("data"); len(b) != 3 || b[0] != byte(n%256) {
+			t.Errorf("%d: invalid data: %v", i, b)
+		}
+		count := n * n * n * n * n * n * n
+		if n%2 == 1 {
+			count = -count
+		}
+		if n == 7 {
+			if row["count"] != nil {
+				t.Errorf("%d: NULL expected: %v", i, row["count"])
+			}
+		} else if row.Int("count") != count {
+			t.Errorf("%d: invalid count: %d instead of %d", i, row.Int("count"), count)
+		}
+		if row["extra"] != nil {
+			t.Errorf("%d: NULL expected: %v", i, row["extra"])
+		}
+	}
+	if last := rows[200]; last.Int("id") != 1000 || last.String("extra") != "extra" {
+		t.Errorf("Invalid last row: %v", last)
+	}
+
+	rows, err = db.Rows("quoted table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].String("a b") != "hello" || rows[0].Int("c") != 42 {
+		t.Errorf("Invalid rows: %v", rows)
+	}
+	if _, err := db.Rows("missing"); !errors.Is(err, ErrNoTable) {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// invalidDatabases returns databases which New or Rows must reject.
+func invalidDatabases(t testing.TB) [][]byte {
+	write := func(tables ...Table) []byte {
+		var buf bytes.Buffer
+		if err := Write(&buf, tables...); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	// A primary key without column: the statement is edited after the
+	// write since Write rejects it.
+	data := write(Table{Name: "t", SQL: "CREATE TABLE t (a INTEGER, PRIMARY KEY (a))"})
+	emptyKey := bytes.Replace(data, []byte("PRIMARY KEY (a)"), []byte("PRIMARY KEY ( )"), 1)
+	// Two tables with the same root page.
+	data = write(Table{Name: "a", SQL: "CREATE TABLE a (x)"},
+		Table{Name: "b", SQL: "CREATE TABLE b (x)"})
+	sharedRoot := append([]byte{}, data...)
+	rootA := bytes.Index(sharedRoot, []byte("tableaa")) + len("tableaa")
+	rootB := bytes.Index(sharedRoot, []byte("tablebb")) + len("tablebb")
+	sharedRoot[rootB] = sharedRoot[rootA]
+	// Many cells of a leaf page point to the same large record.
+	data = write(Table{Name: "t", SQL: "CREATE TABLE t (a)",
+		Rows: [][]interface{}{{strings.Repeat("x", 3000)}}})
+	sameCell := append([]byte{}, data...)
+	leaf := sameCell[writePageSize:]
+	offset := binary.BigEndian.Uint16(leaf[8:])
+	cells := (int(offset) - 8) / 2
+	binary.BigEndian.PutUint16(leaf[3:], uint16(cells))
+	for i := 0; i < cells; i++ {
+		binary.BigEndian.PutUint16(leaf[8+2*i:], offset)
+	}
+	return [][]byte{[]byte("not a database"), emptyKey, sharedRoot, sameCell}
+}
+
+func TestInvalidDatabase(t *testing.T) {
+	for i, data := range invalidDatabases(t) {
+		db, err := New(data)
+		if err == nil {
+			_, err = db.Rows("t")
+		}
+		if !errors.Is(err, ErrFormat) {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+// FuzzNew checks that malformed databases are rejected without panic. The
+// seeds are databases of several pages which are long to minimize: run it
+// with -fuzzminimizetime 2s to keep fuzzing when an input is found.
+func FuzzNew(f *testing.F) {
+	data, err := os.ReadFile("../samples/testdata/test.sqlite")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	for _, invalid := range invalidDatabases(f) {
+		f.Add(invalid)
+	}
+	for _, size := range []int{headerSize, 512, 4096, len(data) / 2, len(data) - 1} {
+		if size < len(data) {
+			f.Add(data[:size])
+		}
+	}
+	for _, offset := range []int{16, 20, 100, 105, 4096 + 8, len(data) - 100} {
+		if offset < len(data) {
+			corrupted := append([]byte{}, data...)
+			corrupted[offset] ^= 0xff
+			f.Add(corrupted)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		db, err := New(data)
+		if err != nil {
+			if !errors.Is(err, ErrFormat) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			return
+		}
+		for _, name := range db.Tables() {
+			if _, err := db.Rows(name); err != nil && !errors.Is(err, ErrFormat) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,362 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+)
+
+// writePageSize is the page size of the databases written.
+const writePageSize = 4096
+
+// Table is a table written by Write. The values of the rows are nil, int,
+// int64, float64, string or []byte, in the order of the columns of the
+// CREATE TABLE statement.
+type Table struct {
+	Name string
+	SQL  string // CREATE TABLE statement
+	Rows [][]interface{}
+}
+
+// writer builds the pages of a database.
+type writer struct {
+	pages [][]byte // pages[0] is the page 1
+}
+
+// leafCell is a cell of a leaf table page.
+type leafCell struct {
+	rowid   int64
+	payload []byte
+}
+
+// child is a page referenced by an interior page with its largest rowid.
+type child struct {
+	page int
+	key  int64
+}
+
+// Create writes a database file with the given tables.
+func Create(filename string, tables ...Table) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, tables...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes a database with the given tables. Indexes aren't supported.
+func Write(w io.Writer, tables ...Table) error {
+	wr := &writer{}
+	wr.allocate() // page 1 holds the schema
+	schema := make([]leafCell, 0, len(tables))
+	for i, t := range tables {
+		cells, err := tableCells(t)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		root := wr.btree(cells)
+		record, err := encodeRecord([]interface{}{"table", t.Name, t.Name,
+			int64(root), t.SQL})
+		if err != nil {
+			return err
+		}
+		schema = append(schema, leafCell{int64(i + 1), record})
+	}
+	cells := make([][]byte, len(schema))
+	size := 0
+	for i, c := range schema {
+		cells[i] = wr.encodeLeafCell(c)
+		size += len(cells[i]) + 2
+	}
+	if headerSize+8+size > writePageSize {
+		return fmt.Errorf("sqlite: schema too large")
+	}
+	fillPage(wr.pages[0], headerSize, leafTable, cells, 0)
+	wr.header()
+	for _, page := range wr.pages {
+		if _, err := w.Write(page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocate adds an empty page and returns its number.
+func (w *writer) allocate() int {
+	w.pages = append(w.pages, make([]byte, writePageSize))
+	return len(w.pages)
+}
+
+// header fills the database header of the page 1.
+func (w *writer) header() {
+	h := w.pages[0][:headerSize]
+	copy(h, magic)
+	binary.BigEndian.PutUint16(h[16:], writePageSize)
+	h[18], h[19] = 1, 1 // legacy journal mode
+	h[21], h[22], h[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(h[24:], 1) // change counter
+	binary.BigEndian.PutUint32(h[28:], uint32(len(w.pages)))
+	binary.BigEndian.PutUint32(h[40:], 1)       // schema cookie
+	binary.BigEndian.PutUint32(h[44:], 4)       // schema format
+	binary.BigEndian.PutUint32(h[56:], 1)       // UTF-8
+	binary.BigEndian.PutUint32(h[92:], 1)       // version valid for
+	binary.BigEndian.PutUint32(h[96:], 3045000) // SQLite version
+}
+
+// tableCells returns the cells of a table sorted by rowid. The INTEGER
+// PRIMARY KEY column is the rowid, the other rows are numbered from 1.
+func tableCells(t Table) ([]leafCell, error) {
+	columns, rowidColumn, err := parseColumns(t.SQL)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("sqlite: invalid statement: %s", t.SQL)
+	}
+	cells := make([]leafCell, len(t.Rows))
+	for i, row := range t.Rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("sqlite: row %d has %d values, %d expected",
+				i, len(row), len(columns))
+		}
+		cells[i].rowid = int64(i + 1)
+		if rowidColumn >= 0 {
+			rowid, ok := integer(row[rowidColumn])
+			if !ok {
+				return nil, fmt.Errorf("sqlite: row %d: invalid %s: %v", i,
+					columns[rowidColumn], row[rowidColumn])
+			}
+			cells[i].rowid = rowid
+			row = append([]interface{}{}, row...)
+			row[rowidColumn] = nil
+		}
+		record, err := encodeRecord(row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		cells[i].payload = record
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i].rowid < cells[j].rowid })
+	for i := 1; i < len(cells); i++ {
+		if cells[i].rowid == cells[i-1].rowid {
+			return nil, fmt.Errorf("sqlite: duplicate rowid %d", cells[i].rowid)
+		}
+	}
+	return cells, nil
+}
+
+// btree writes the pages of a table b-tree and returns its root page.
+func (w *writer) btree(cells []leafCell) int {
+	space := writePageSize - 8
+	children := make([]child, 0)
+	page := make([][]byte, 0)
+	used := 0
+	flush := func(key int64) {
+		number := w.allocate()
+		fillPage(w.pages[number-1], 0, leafTable, page, 0)
+		children = append(children, child{number, key})
+		page, used = make([][]byte, 0), 0
+	}
+	for i, c := range cells {
+		cell := w.encodeLeafCell(c)
+		if used+len(cell)+2 > space && len(page) != 0 {
+			flush(cells[i-1].rowid)
+		}
+		page = append(page, cell)
+		used += len(cell) + 2
+	}
+	if len(page) != 0 || len(children) == 0 {
+		var key int64
+		if len(cells) != 0 {
+			key = cells[len(cells)-1].rowid
+		}
+		flush(key)
+	}
+	for len(children) > 1 {
+		children = w.interior(children)
+	}
+	return children[0].page
+}
+
+// interior writes a level of interior pages above children and returns
+// the pages written.
+func (w *writer) interior(children []child) []child {
+	space := writePageSize - 12
+	parents := make([]child, 0)
+	for len(children) != 0 {
+		cells := make([][]byte, 0)
+		used := 0
+		n := 0
+		// The last child of the page is its right pointer.
+		for n < len(children)-1 {
+			cell := interiorCell(children[n])
+			if used+len(cell)+2 > space {
+				break
+			}
+			cells = append(cells, cell)
+			used += len(cell) + 2
+			n++
+		}
+		right := children[n]
+		number := w.allocate()
+		fillPage(w.pages[number-1], 0, interiorTable, cells, uint32(right.page))
+		parents = append(parents, child{number, right.key})
+		children = children[n+1:]
+	}
+	return parents
+}
+
+// interiorCell encodes a cell of an interior table page.
+func interiorCell(c child) []byte {
+	cell := make([]byte, 4, 13)
+	binary.BigEndian.PutUint32(cell, uint32(c.page))
+	return appendVarint(cell, uint64(c.key))
+}
+
+// encodeLeafCell encodes a cell of a leaf table page and writes the
+// overflow pages of its payload.
+func (w *writer) encodeLeafCell(c leafCell) []byte {
+	size := len(c.payload)
+	cell := appendVarint(nil, uint64(size))
+	cell = appendVarint(cell, uint64(c.rowid))
+	local := localSize(size, writePageSize)
+	cell = append(cell, c.payload[:local]...)
+	if local == size {
+		return cell
+	}
+	rest := c.payload[local:]
+	first := w.allocate()
+	cell = binary.BigEndian.AppendUint32(cell, uint32(first))
+	for number := first; ; {
+		page := w.pages[number-1]
+		n := copy(page[4:], rest)
+		rest = rest[n:]
+		if len(rest) == 0 {
+			break
+		}
+		number = w.allocate()
+		binary.BigEndian.PutUint32(page, uint32(number))
+	}
+	return cell
+}
+
+// fillPage writes a b-tree page header at offset, followed by the cell
+// pointers. The cells are stored at the end of the page.
+func fillPage(page []byte, offset int, kind byte, cells [][]byte, right uint32) {
+	page[offset] = kind
+	binary.BigEndian.PutUint16(page[offset+3:], uint16(len(cells)))
+	pointers := offset + 8
+	if kind == interiorTable {
+		binary.BigEndian.PutUint32(page[offset+8:], right)
+		pointers = offset + 12
+	}
+	content := len(page)
+	for i, cell := range cells {
+		content -= len(cell)
+		copy(page[content:], cell)
+		binary.BigEndian.PutUint16(page[pointers+2*i:], uint16(content))
+	}
+	binary.BigEndian.PutUint16(page[offset+5:], uint16(content))
+}
+
+// integer returns the value of an integer.
+func integer(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// encodeRecord encodes values into a record.
+func encodeRecord(values []interface{}) ([]byte, error) {
+	types := make([]byte, 0, len(values))
+	body := make([]byte, 0)
+	for _, value := range values {
+		var t uint64
+		switch v := value.(type) {
+		case nil:
+			t = 0
+		case int, int64:
+			i, _ := integer(v)
+			t, body = appendInt(body, i)
+		case float64:
+			t = 7
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			t = uint64(13 + 2*len(v))
+			body = append(body, v...)
+		case []byte:
+			t = uint64(12 + 2*len(v))
+			body = append(body, v...)
+		default:
+			return nil, fmt.Errorf("sqlite: unsupported value type %T", value)
+		}
+		types = appendVarint(types, t)
+	}
+	// The size of the header includes its own varint.
+	size := len(types) + 1
+	for len(appendVarint(nil, uint64(size))) != size-len(types) {
+		size++
+	}
+	record := appendVarint(nil, uint64(size))
+	record = append(record, types...)
+	return append(record, body...), nil
+}
+
+// appendInt appends the smallest encoding of an integer and returns its
+// serial type.
+func appendInt(b []byte, v int64) (uint64, []byte) {
+	switch {
+	case v == 0:
+		return 8, b
+	case v == 1:
+		return 9, b
+	}
+	sizes := []struct {
+		t    uint64
+		size int
+	}{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 6}}
+	for _, s := range sizes {
+		bits := uint(8*s.size - 1)
+		if v >= -1<<bits && v < 1<<bits {
+			for i := s.size - 1; i >= 0; i-- {
+				b = append(b, byte(v>>(8*uint(i))))
+			}
+			return s.t, b
+		}
+	}
+	return 6, binary.BigEndian.AppendUint64(b, uint64(v))
+}
+
+// appendVarint appends a SQLite variable length integer.
+func appendVarint(b []byte, v uint64) []byte {
+	if v > 1<<56-1 {
+		// 8 bytes of 7 bits followed by a byte of 8 bits.
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	var buf [8]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v != 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[i:]...)
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteTable(t *testing.T) {
+	items := Table{
+		Name: "items",
+		SQL:  "CREATE TABLE items (id integer primary key, name text, value real, data blob, count integer)",
+	}
+	// Enough rows to need two levels of interior pages.
+	const rowsNb = 30000
+	for i := rowsNb; i > 0; i-- {
+		name := "item " + strings.Repeat("x", i%10)
+		if i%1000 == 0 {
+			name = strings.Repeat("long ", 3000) // overflow pages
+		}
+		var count interface{} = int64(i) * int64(i) * int64(i) * int64(i) * int64(i)
+		if i%2 == 0 {
+			count = -count.(int64)
+		} else if i%7 == 0 {
+			count = nil
+		}
+		items.Rows = append(items.Rows, []interface{}{2 * i, name,
+			float64(i) / 4, []byte{byte(i), 2, 3}, count})
+	}
+	empty := Table{Name: "empty", SQL: `CREATE TABLE "empty" ("a b" text, c integer)`}
+	values := Table{
+		Name: "values",
+		SQL:  "CREATE TABLE \"values\" (a integer, b text)",
+		Rows: [][]interface{}{
+			{int64(math.MinInt64), "min"},
+			{int64(math.MaxInt64), "max"},
+			{0, "zero"},
+			{1, "one"},
+			{-129, "byte"},
+			{1 << 40, "six bytes"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, items, empty, values); err != nil {
+		t.Fatal(err)
+	}
+	db, err := New(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tables := db.Tables(); !reflect.DeepEqual(tables, []string{"empty", "items", "values"}) {
+		t.Errorf("Invalid tables: %v", tables)
+	}
+	rows, err := db.Rows("items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != rowsNb {
+		t.Fatalf("Invalid number of rows: %d", len(rows))
+	}
+	for i, row := range rows {
+		expected := items.Rows[rowsNb-1-i]
+		actual := []interface{}{int(row.Int("id")), row["name"], row["value"],
+			row["data"], row["count"]}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%d: invalid row: %v instead of %v", i, actual, expected)
+		}
+	}
+	if rows, err := db.Rows("empty"); err != nil || len(rows) != 0 {
+		t.Errorf("Invalid empty table: %v, %v", rows, err)
+	}
+	rows, err = db.Rows("values")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rows {
+		a, _ := integer(values.Rows[i][0])
+		if row.Int("a") != a || row.String("b") != values.Rows[i][1] {
+			t.Errorf("%d: invalid row: %v", i, row)
+		}
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	tables := []Table{
+		{Name: "t", SQL: "CREATE TABLE t (id integer primary key)", Rows: [][]interface{}{{1}, {1}}},
+		{Name: "t", SQL: "CREATE TABLE t (id integer primary key)", Rows: [][]interface{}{{"a"}}},
+		{Name: "t", SQL: "CREATE TABLE t (a, b)", Rows: [][]interface{}{{1}}},
+		{Name: "t", SQL: "CREATE TABLE t (a)", Rows: [][]interface{}{{true}}},
+		{Name: "t", SQL: "invalid"},
+	}
+	for _, table := range tables {
+		if err := Write(&bytes.Buffer{}, table); err == nil {
+			t.Errorf("%v: error expected", table)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.db")
+	table := Table{Name: "t", SQL: "CREATE TABLE t (a text)", Rows: [][]interface{}{{"hello"}}}
+	if err := Create(filename, table); err != nil {
+		t.Fatal(err)
+	}
+	db, err := Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, err := db.Rows("t"); err != nil || len(rows) != 1 || rows[0].String("a") != "hello" {
+		t.Errorf("Invalid rows: %v, %v", rows, err)
+	}
+}
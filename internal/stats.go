@@ -0,0 +1,216 @@
+package flashdown
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// MatureInterval is the interval in days above which a card is mature.
+const MatureInterval = 21
+
+// CardState tells if a card is studied for the first time or reviewed.
+type CardState int
+
+const (
+	StateNew    CardState = iota // never reviewed
+	StateReview                  // reviewed at least once
+)
+
+func (s CardState) String() string {
+	if s == StateNew {
+		return "new"
+	}
+	return "review"
+}
+
+// IsNew returns true if the card has never been reviewed.
+func (c *Meta) IsNew() bool {
+	return c.Repetition == 0 && c.LastTime.IsZero()
+}
+
+// State returns StateNew if the card has never been reviewed, StateReview
+// otherwise.
+func (c *Meta) State() CardState {
+	if c.IsNew() {
+		return StateNew
+	}
+	return StateReview
+}
+
+// Interval returns the number of days between the last review and the next
+// one. For cards reviewed before the last review time was recorded, it is
+// estimated from the number of repetitions.
+func (c *Meta) Interval() float64 {
+	if c.LastTime.IsZero() {
+		if c.Repetition == 0 {
+			return 0
+		}
+		return float64(NewSM2Scheduler().interval(c.Repetition, c.Easiness))
+	}
+	return daysBetween(c.LastTime, c.NextTime)
+}
+
+// DeckStats summarizes the progress made on a deck.
+type DeckStats struct {
+	Name            string
+	Cards           int
+	DueToday        int // due before the end of the day
+	DueWeek         int // due within the next 7 days
+	DueMonth        int // due within the next 30 days
+	New             int // never reviewed
+	Learning        int // interval under MatureInterval days
+	Mature          int // interval of MatureInterval days or more
+	AverageEasiness float64
+	Reviews         int     // reviews of cards previously learned
+	Recalled        int     // successful reviews among Reviews
+	Retention       float64 // Recalled / Reviews
+}
+
+// endOfDay returns the midnight following t.
+func endOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+}
+
+// NewDeckStats computes the statistics of a deck at a given time. The
+// suspended cards are never due and the buried cards are due once their
+// burial ends, like in a session.
+func NewDeckStats(deck *Deck, now time.Time) (DeckStats, error) {
+	stats := DeckStats{
+		Name:  deck.Name,
+		Cards: len(deck.Cards),
+	}
+	today := endOfDay(now)
+	easiness := 0.0
+	for _, card := range deck.Cards {
+		meta := card.Meta
+		due := meta.NextTime
+		if meta.IsBuried(now) && due.Before(*meta.Buried) {
+			due = *meta.Buried
+		}
+		if !meta.Suspended && due.Before(today) {
+			stats.DueToday++
+		}
+		if !meta.Suspended && due.Before(today.AddDate(0, 0, 7)) {
+			stats.DueWeek++
+		}
+		if !meta.Suspended && due.Before(today.AddDate(0, 0, 30)) {
+			stats.DueMonth++
+		}
+		switch {
+		case meta.IsNew():
+			stats.New++
+		case meta.Interval() < MatureInterval:
+			stats.Learning++
+		default:
+			stats.Mature++
+		}
+		easiness += float64(meta.Easiness)
+	}
+	if stats.Cards != 0 {
+		stats.AverageEasiness = easiness / float64(stats.Cards)
+	}
+
+	logs, err := deck.History()
+	if err != nil {
+		return stats, err
+	}
+	for _, log := range logs {
+		if log.PreviousInterval < 1 {
+			continue // new or relearning card
+		}
+		stats.Reviews++
+		if log.Score >= CorrectDifficult {
+			stats.Recalled++
+		}
+	}
+	stats.updateRetention()
+	return stats, nil
+}
+
+func (s *DeckStats) updateRetention() {
+	s.Retention = 0
+	if s.Reviews != 0 {
+		s.Retention = float64(s.Recalled) / float64(s.Reviews)
+	}
+}
+
+// TotalStats aggregates the statistics of several decks.
+func TotalStats(name string, stats ...DeckStats) DeckStats {
+	total := DeckStats{Name: name}
+	easiness := 0.0
+	for _, s := range stats {
+		total.Cards += s.Cards
+		total.DueToday += s.DueToday
+		total.DueWeek += s.DueWeek
+		total.DueMonth += s.DueMonth
+		total.New += s.New
+		total.Learning += s.Learning
+		total.Mature += s.Mature
+		total.Reviews += s.Reviews
+		total.Recalled += s.Recalled
+		easiness += s.AverageEasiness * float64(s.Cards)
+	}
+	if total.Cards != 0 {
+		total.AverageEasiness = easiness / float64(total.Cards)
+	}
+	total.updateRetention()
+	return total
+}
+
+// daysFrom returns the number of calendar days from now to t.
+func daysFrom(now, t time.Time) int {
+	from := endOfDay(now)
+	to := endOfDay(t.In(now.Location()))
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// Forecast returns the number of cards due each day starting today. The
+// cards overdue are counted today, the suspended cards aren't counted.
+func Forecast(decks []*Deck, now time.Time, days int) []int {
+	forecast := make([]int, days)
+	for _, deck := range decks {
+		for _, card := range deck.Cards {
+			if card.Meta.Suspended {
+				continue
+			}
+			day := daysFrom(now, card.Meta.NextTime)
+			if day < 0 {
+				day = 0
+			}
+			if day < days {
+				forecast[day]++
+			}
+		}
+	}
+	return forecast
+}
+
+// ReviewsPerDay returns the number of reviews made each day of the period
+// ending today, the oldest day first.
+func ReviewsPerDay(logs []ReviewLog, now time.Time, days int) []int {
+	activity := make([]int, days)
+	for _, log := range logs {
+		day := days - 1 + daysFrom(now, log.Time)
+		if day >= 0 && day < days {
+			activity[day]++
+		}
+	}
+	return activity
+}
+
+// Histogram counts how many values falls into each bucket. The bucket i
+// holds the values lower than bounds[i] and not in a previous bucket. The
+// last bucket holds the values greater or equal to the last bound.
+func Histogram(values []float64, bounds []float64) []int {
+	buckets := make([]int, len(bounds)+1)
+	for _, v := range values {
+		i := sort.SearchFloat64s(bounds, v)
+		if i < len(bounds) && bounds[i] == v {
+			i++
+		}
+		buckets[i]++
+	}
+	return buckets
+}
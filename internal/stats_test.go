@@ -0,0 +1,136 @@
+package flashdown
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeckStats(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+## question 3
+answer 3
+`)
+	deck.Scheduler = NewSM2Scheduler()
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{Clock: clock}, deck)
+	game.Review(PerfectRecall)
+	game.Review(TotalBlackout)
+
+	stats, err := NewDeckStats(deck, clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DeckStats{
+		Name:            "memory.md",
+		Cards:           3,
+		DueToday:        2,
+		DueWeek:         3,
+		DueMonth:        3,
+		New:             1,
+		Learning:        2,
+		AverageEasiness: stats.AverageEasiness,
+	}
+	if stats != expected {
+		t.Errorf("Invalid stats: %+v", stats)
+	}
+
+	// Review the learned card after FirstRepetitionDelay days.
+	for _, card := range deck.Cards {
+		if card.Meta.Repetition == 1 {
+			clock.Set(card.Meta.NextTime)
+			card.Review(IncorrectEasy, clock.Now())
+			log := newReviewLog(Meta{}, *card.Meta, IncorrectEasy, clock.Now(), 0)
+			log.PreviousInterval = FirstRepetitionDelay
+			if err := deck.AppendHistory(log); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	stats, err = NewDeckStats(deck, clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Reviews != 1 || stats.Recalled != 0 || stats.Retention != 0 {
+		t.Errorf("Invalid retention: %+v", stats)
+	}
+	total := TotalStats("total", stats, stats)
+	if total.Cards != 6 || total.Reviews != 2 ||
+		total.AverageEasiness != stats.AverageEasiness {
+		t.Errorf("Invalid total: %+v", total)
+	}
+}
+
+func TestDeckStatsHiddenCards(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+## question 3
+answer 3
+`)
+	deck.Cards[0].Meta.Suspended = true
+	deck.Cards[1].Meta.Bury(clock.Now())
+	stats, err := NewDeckStats(deck, clock.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.DueToday != 1 || stats.DueWeek != 2 || stats.DueMonth != 2 {
+		t.Errorf("Invalid due cards: %+v", stats)
+	}
+	if due := len(deck.SelectBefore(clock.Now().Add(time.Minute))); due != stats.DueToday {
+		t.Errorf("%d cards due in a session, %d in the statistics", due, stats.DueToday)
+	}
+}
+
+func TestForecast(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	clock := NewFixedClock(now)
+	deck := newMemoryDeck(t, clock, `
+## question 1
+answer 1
+## question 2
+answer 2
+## question 3
+answer 3
+`)
+	deck.Cards[0].Meta.NextTime = now.AddDate(0, 0, -3)
+	deck.Cards[1].Meta.NextTime = now.Add(20 * time.Hour)
+	deck.Cards[2].Meta.NextTime = now.AddDate(0, 0, 40)
+	forecast := Forecast([]*Deck{deck, deck}, now, 30)
+	if len(forecast) != 30 || forecast[0] != 2 || forecast[1] != 2 {
+		t.Errorf("Invalid forecast: %v", forecast)
+	}
+
+	logs := []ReviewLog{
+		{Time: now.Add(-time.Hour)},
+		{Time: now.AddDate(0, 0, -1)},
+		{Time: now.AddDate(0, 0, -1)},
+		{Time: now.AddDate(0, 0, -10)},
+	}
+	activity := ReviewsPerDay(logs, now, 7)
+	expected := []int{0, 0, 0, 0, 0, 2, 1}
+	for i := range expected {
+		if activity[i] != expected[i] {
+			t.Errorf("Invalid activity: %v", activity)
+			break
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	buckets := Histogram([]float64{0, 1, 1.5, 2, 7, 30}, []float64{1, 2, 7})
+	expected := []int{1, 2, 1, 2}
+	for i := range expected {
+		if buckets[i] != expected[i] {
+			t.Errorf("Invalid histogram: %v", buckets)
+			break
+		}
+	}
+}
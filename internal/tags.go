@@ -0,0 +1,108 @@
+package flashdown
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// headingTags matches the "#tag" tokens at the end of a question.
+	headingTags = regexp.MustCompile(`(\s+#\pL[\pL\pN_/-]*)+\s*$`)
+	// tagsLine matches a "tags: a, b" line right after the question.
+	tagsLine = regexp.MustCompile(`(?i)^tags:(.*)$`)
+)
+
+// parseHeadingTags removes the trailing "#tag" tokens from a question and
+// returns them.
+func parseHeadingTags(question string) (string, []string) {
+	match := headingTags.FindString(question)
+	if match == "" {
+		return question, nil
+	}
+	tags := make([]string, 0)
+	for _, token := range strings.Fields(match) {
+		tags = append(tags, strings.TrimPrefix(token, "#"))
+	}
+	return trim(strings.TrimSuffix(question, match)), tags
+}
+
+// parseTagsLine removes the "tags:" line at the beginning of an answer and
+// returns the tags it lists, separated by commas or spaces.
+func parseTagsLine(answer string) (string, []string) {
+	lines := strings.SplitN(answer, "\n", 2)
+	match := tagsLine.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if match == nil {
+		return answer, nil
+	}
+	tags := strings.FieldsFunc(match[1], func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	for i := range tags {
+		tags[i] = strings.TrimPrefix(tags[i], "#")
+	}
+	if len(lines) == 1 {
+		return "", tags
+	}
+	return trim(lines[1]), tags
+}
+
+// mergeTags returns the tags of both lists without duplicates. Like HasTag,
+// it ignores the case.
+func mergeTags(a, b []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	tags := make([]string, 0, len(a)+len(b))
+	for _, tag := range append(append([]string{}, a...), b...) {
+		if !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// HasTag returns true if the card is tagged with tag (case insensitive).
+func (c Card) HasTag(tag string) bool {
+	for _, t := range c.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// TagFilter returns a filter which selects the cards with at least one of
+// the tags.
+func TagFilter(tags ...string) func(Card) bool {
+	return func(c Card) bool {
+		for _, tag := range tags {
+			if c.HasTag(tag) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Tags returns the tags used by the cards of the decks in lower case, since
+// tags are case insensitive, sorted.
+func Tags(decks ...*Deck) []string {
+	seen := make(map[string]bool)
+	tags := make([]string, 0)
+	for _, deck := range decks {
+		for _, card := range deck.Cards {
+			for _, tag := range card.Tags {
+				tag = strings.ToLower(tag)
+				if !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
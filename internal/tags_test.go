@@ -0,0 +1,124 @@
+package flashdown
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCardTags(t *testing.T) {
+	input := `---
+tags: [latency]
+---
+## L1 cache reference #cpu #cache
+0.5 ns
+
+## Round trip within the same datacenter
+tags: networking, datacenter
+500 µs
+
+## Issue #42 of C#
+fixed
+`
+	cards, err := readCards(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Card{
+		{
+			Question: "L1 cache reference",
+			Answer:   "0.5 ns",
+			Tags:     []string{"latency", "cpu", "cache"},
+		},
+		{
+			Question: "Round trip within the same datacenter",
+			Answer:   "500 µs",
+			Tags:     []string{"latency", "networking", "datacenter"},
+		},
+		{
+			Question: "Issue #42 of C#",
+			Answer:   "fixed",
+			Tags:     []string{"latency"},
+		},
+	}
+	if len(cards) != len(expected) {
+		t.Fatalf("Wrong length: %d", len(cards))
+	}
+	for i, card := range cards {
+		if card.Question != expected[i].Question {
+			t.Errorf("%d: question: %q", i, card.Question)
+		}
+		if card.Answer != expected[i].Answer {
+			t.Errorf("%d: answer: %q", i, card.Answer)
+		}
+		if !reflect.DeepEqual(card.Tags, expected[i].Tags) {
+			t.Errorf("%d: tags: %v", i, card.Tags)
+		}
+	}
+	if Hash(cards[0]) != Hash(Card{Question: "L1 cache reference"}) {
+		t.Errorf("Tags must not change the card digest")
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	clock := NewFixedClock(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	deck1 := newMemoryDeck(t, clock, `
+## question 1 #networking
+answer 1
+## question 2
+answer 2
+`)
+	deck2 := newMemoryDeck(t, clock, `
+## question 3 #Networking #dns
+answer 3
+## question 4 #cpu
+answer 4
+`)
+	if tags := Tags(deck1, deck2); !reflect.DeepEqual(tags,
+		[]string{"cpu", "dns", "networking"}) {
+		t.Errorf("Invalid tags: %v", tags)
+	}
+	clock.Add(time.Minute)
+	game := NewGameWithOptions(GameOptions{
+		Clock:  clock,
+		Filter: TagFilter("networking"),
+	}, deck1, deck2)
+	if _, total := game.Progress(); total != 2 {
+		t.Errorf("Invalid number of cards: %d", total)
+	}
+	for !game.IsFinished() {
+		if q := game.Question(); q != "question 1" && q != "question 3" {
+			t.Errorf("Unexpected card: %s", q)
+		}
+		game.Review(Score(5))
+	}
+}
+
+func TestTagsKeepProgress(t *testing.T) {
+	// Before the tags were parsed, they were part of the digest.
+	accessor := &memoryAccessor{name: "memory.md", cards: `
+## L1 cache reference #cpu
+0.5 ns
+## The capital of {{c1::France}} is {{c2::Paris}} #geography
+`}
+	metas := []Meta{
+		{Hash: textHash("L1 cache reference #cpu"), Repetition: 3},
+		{Hash: textHash("The capital of [...] is Paris #geography"), Repetition: 2},
+	}
+	if err := writeDB(&accessor.meta, metaDB{Cards: metas}); err != nil {
+		t.Fatal(err)
+	}
+	deck, err := NewDeck(accessor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, repetition := range []int32{3, 2, 0} {
+		if deck.Cards[i].Meta.Repetition != repetition {
+			t.Errorf("%d: progress lost: %d", i, deck.Cards[i].Meta.Repetition)
+		}
+		if deck.Cards[i].Meta.Hash != Hash(deck.Cards[i]) {
+			t.Errorf("%d: digest not migrated", i)
+		}
+	}
+}